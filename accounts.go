@@ -1,26 +1,36 @@
 package coinbasetrade
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
 type Account struct {
-	ID               string    `json:"uuid"`
-	Name             string    `json:"name"`
-	Currency         string    `json:"currency"`
-	AvailableBalance Balance   `json:"available_balance"`
-	Default          bool      `json:"default"`
-	Active           bool      `json:"active"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
-	DeletedAt        time.Time `json:"deleted_at"`
-	Type             string    `json:"type"`
-	Ready            bool      `json:"ready"`
-	HoldBalance      Balance   `json:"hold"`
+	ID                string    `json:"uuid"`
+	Name              string    `json:"name"`
+	Currency          string    `json:"currency"`
+	AvailableBalance  Balance   `json:"available_balance"`
+	Default           bool      `json:"default"`
+	Active            bool      `json:"active"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	DeletedAt         time.Time `json:"deleted_at"`
+	Type              string    `json:"type"`
+	Ready             bool      `json:"ready"`
+	HoldBalance       Balance   `json:"hold"`
+	RetailPortfolioID string    `json:"retail_portfolio_id"` // the portfolio the account belongs to
+	Platform          string    `json:"platform"`            // i.e. "ACCOUNT_PLATFORM_CONSUMER" or "ACCOUNT_PLATFORM_CFM_CONSUMER" for futures
+
+	Raw json.RawMessage `json:"-"` // the account as it was received, if the client's KeepRaw is set
 }
 
 type Balance struct {
@@ -30,11 +40,31 @@ type Balance struct {
 
 type AccountList struct {
 	Accounts []Account `json:"accounts"`
-	Pagination
+	Pagination[ListAccountsParameters, Account]
+}
+
+// NextPage replaces the accounts in the list with the next page of results, or appends the page to
+// them if Accumulate is set.
+func (l *AccountList) NextPage() error {
+	_, err := l.FetchPage()
+	return err
+}
+
+// FetchPage works like NextPage, and also returns the accounts in the page that was fetched. It returns
+// no accounts once there are no more pages.
+func (l *AccountList) FetchPage() ([]Account, error) {
+	return l.nextPage(context.Background(), &l.Accounts)
+}
+
+// All returns the accounts in the current page and every page after it, up to the given limits.
+func (l *AccountList) All(ctx context.Context, limits AllLimits) ([]Account, error) {
+	return l.all(ctx, limits, &l.Accounts)
 }
 
 type ListAccountsParameters struct {
-	Limit int `cbt:"limit"`
+	Limit             int    `cbt:"limit"`
+	Cursor            string `cbt:"cursor"`              // start listing from a cursor saved from an earlier list
+	RetailPortfolioID string `cbt:"retail_portfolio_id"` // only list the accounts in this portfolio
 }
 
 // ListAccounts takes parameters (ListAccountsParameters), and returns an AccountsList. The
@@ -42,16 +72,114 @@ type ListAccountsParameters struct {
 // results can be retrieved by calling NextPage(). Next() will show if there are more pages
 // to be retrieved.
 func (c *Client) ListAccounts(params ListAccountsParameters) (l AccountList, err error) {
-	l.Pagination = Pagination{
-		client:     c,
-		parent:     &l,
-		parameters: params,
+	l.Pagination = Pagination[ListAccountsParameters, Account]{pager: c.accountsPager(params)}
+
+	err = l.NextPage()
+	return
+}
+
+// IterAccounts returns an iterator over every account, fetching pages as needed.
+func (c *Client) IterAccounts(params ListAccountsParameters) *Iterator[Account] {
+	return newIterator(c.accountsPager(params))
+}
+
+// StreamAccounts sends every account on a channel as the pages are fetched. See Iterator.Stream.
+func (c *Client) StreamAccounts(ctx context.Context, params ListAccountsParameters) (<-chan Account, <-chan error) {
+	return c.IterAccounts(params).Stream(ctx)
+}
+
+// Accounts returns every account as a sequence for use with range, fetching pages as needed:
+//
+//	for a, err := range client.Accounts(ctx, params) {
+//		...
+//	}
+func (c *Client) Accounts(ctx context.Context, params ListAccountsParameters) iter.Seq2[Account, error] {
+	return c.IterAccounts(params).Seq(ctx)
+}
+
+func (c *Client) accountsPager(params ListAccountsParameters) pager[ListAccountsParameters, Account] {
+	return pager[ListAccountsParameters, Account]{
+		client: c,
+		params: params,
 
 		method:   Get,
 		endpoint: listAccountsEndpoint,
+		key:      "accounts",
 	}
+}
 
-	err = l.NextPage()
+// allAccounts fetches every account
+func (c *Client) allAccounts() (accounts []Account, err error) {
+	it := c.IterAccounts(ListAccountsParameters{Limit: 250})
+	for it.Next() {
+		accounts = append(accounts, it.Value())
+	}
+	if err = it.Err(); err == nil {
+		c.accountIDs.remember(accounts)
+	}
+	return
+}
+
+// ErrMultipleAccounts is returned by GetAccountByCurrency when the currency has an account in more
+// than one portfolio. Use ListAccounts with RetailPortfolioID to pick one.
+var ErrMultipleAccounts = errors.New("currency has accounts in more than one portfolio")
+
+// accountIDCache maps currencies to the ids of their accounts, which don't change
+type accountIDCache struct {
+	mu  sync.Mutex
+	ids map[string][]string
+}
+
+// remember records the ids of the accounts for each currency, across all portfolios
+func (a *accountIDCache) remember(accounts []Account) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.ids = make(map[string][]string, len(accounts))
+	for _, acc := range accounts {
+		currency := strings.ToUpper(acc.Currency)
+		a.ids[currency] = append(a.ids[currency], acc.ID)
+	}
+}
+
+// get returns the ids of a currency's accounts, if they are known
+func (a *accountIDCache) get(currency string) (ids []string, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids, ok = a.ids[strings.ToUpper(currency)]
+	return
+}
+
+// GetAccountByCurrency returns the account for a currency (i.e. "BTC"), with its current balances.
+// The first call lists every account to find the account ids, which are cached; later calls get the
+// account directly. If there is no account for the currency, the error unwraps to ErrNotFound, and
+// if there are accounts for it in more than one portfolio, it unwraps to ErrMultipleAccounts.
+func (c *Client) GetAccountByCurrency(currency string) (acc Account, err error) {
+	if ids, ok := c.accountIDs.get(currency); ok {
+		if len(ids) > 1 {
+			err = formatError("get account by currency", fmt.Errorf("%d %s accounts: %w", len(ids), currency, ErrMultipleAccounts))
+			return
+		}
+		return c.GetAccount(ids[0])
+	}
+
+	var accounts, matches []Account
+	if accounts, err = c.allAccounts(); err != nil {
+		return
+	}
+	for _, a := range accounts {
+		if strings.EqualFold(a.Currency, currency) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		err = formatError("get account by currency", fmt.Errorf("no %s account: %w", currency, ErrNotFound))
+	case 1:
+		acc = matches[0]
+	default:
+		err = formatError("get account by currency", fmt.Errorf("%d %s accounts: %w", len(matches), currency, ErrMultipleAccounts))
+	}
 	return
 }
 
@@ -61,6 +189,10 @@ func (c *Client) GetAccount(id string) (acc Account, err error) {
 		Account *Account `json:"account"`
 	}{&acc}
 
-	_, err = c.makeRequest(Get, fmt.Sprintf(getAccountEndpoint, id), url.Values{}, []byte{}, wrapper, nil)
+	var data []byte
+	if data, err = c.makeRequest(context.Background(), Get, fmt.Sprintf(getAccountEndpoint, id), url.Values{}, []byte{}, wrapper, nil); err != nil {
+		return
+	}
+	acc.Raw = c.rawItem(data, "account")
 	return
 }
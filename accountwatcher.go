@@ -0,0 +1,150 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AccountChange describes an account whose available or held balance has changed. Previous is
+// empty when the account is new.
+type AccountChange struct {
+	Previous Account
+	Current  Account
+}
+
+// AccountWatcher keeps a copy of the user's accounts up to date, and calls OnChange whenever an
+// account's available or held balance changes. Accounts are fetched every Interval while Run is
+// going, and also soon after any order update from the user websocket channel is passed to Apply,
+// so balances are picked up quickly after trades as well as after deposits and settlement. It is
+// safe to use from multiple goroutines.
+type AccountWatcher struct {
+	Interval time.Duration       // how often to fetch the accounts (default 1 minute)
+	OnChange func(AccountChange) // called for each changed account, in the goroutine that fetched them
+	OnError  func(error)         // called when fetching the accounts fails during Run
+
+	client   *Client
+	mu       sync.Mutex
+	accounts map[string]Account
+	loaded   bool
+	trigger  chan struct{}
+}
+
+// NewAccountWatcher creates an AccountWatcher that uses this client to fetch the accounts.
+func (c *Client) NewAccountWatcher(onChange func(AccountChange)) *AccountWatcher {
+	return &AccountWatcher{
+		Interval: time.Minute,
+		OnChange: onChange,
+		client:   c,
+		accounts: make(map[string]Account),
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Refresh fetches the accounts now, and calls OnChange for any that have changed. Nothing is
+// reported the first time the accounts are fetched, since there is nothing to compare them to.
+func (w *AccountWatcher) Refresh() (err error) {
+	var accounts []Account
+	if accounts, err = w.client.allAccounts(); err != nil {
+		return
+	}
+
+	var changes []AccountChange
+	w.mu.Lock()
+	for _, a := range accounts {
+		prev, ok := w.accounts[a.ID]
+		w.accounts[a.ID] = a
+		if !w.loaded {
+			continue
+		}
+		if !ok || !prev.AvailableBalance.Value.Equal(a.AvailableBalance.Value) || !prev.HoldBalance.Value.Equal(a.HoldBalance.Value) {
+			changes = append(changes, AccountChange{Previous: prev, Current: a})
+		}
+	}
+	w.loaded = true
+	w.mu.Unlock()
+
+	if w.OnChange != nil {
+		for _, c := range changes {
+			w.OnChange(c)
+		}
+	}
+	return
+}
+
+// Apply schedules a refresh when a user channel message with order updates is received. Other
+// messages are ignored. The refresh itself is done by Run, so this never blocks.
+func (w *AccountWatcher) Apply(msg WebsocketMessage) {
+	if msg.Channel != UserChannel {
+		return
+	}
+
+	events, err := msg.UserEvents()
+	if err != nil {
+		return
+	}
+	for _, e := range events {
+		if e.Type == "update" && len(e.Orders) > 0 {
+			w.Trigger()
+			return
+		}
+	}
+}
+
+// Trigger schedules a refresh by Run as soon as possible.
+func (w *AccountWatcher) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+		// a refresh is already pending
+	}
+}
+
+// Run fetches the accounts straight away, then every Interval and whenever a refresh is
+// triggered, until the context is done.
+func (w *AccountWatcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Refresh(); err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-w.trigger:
+		}
+	}
+}
+
+// Get returns the latest copy of an account, and false if it hasn't been seen.
+func (w *AccountWatcher) Get(id string) (a Account, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	a, ok = w.accounts[id]
+	return
+}
+
+// Accounts returns the latest copy of every account, sorted by currency.
+func (w *AccountWatcher) Accounts() (accounts []Account) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, a := range w.accounts {
+		accounts = append(accounts, a)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if accounts[i].Currency != accounts[j].Currency {
+			return accounts[i].Currency < accounts[j].Currency
+		}
+		return accounts[i].ID < accounts[j].ID
+	})
+	return
+}
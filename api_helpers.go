@@ -15,6 +15,18 @@ func timeToString(t time.Time) string {
 	return t.Format(time.RFC3339)
 }
 
+// parseOptionalTime parses an RFC 3339 time, returning the zero time if it is empty or invalid
+func parseOptionalTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// parseOptionalDecimal parses a decimal, returning zero if it is empty or invalid
+func parseOptionalDecimal(s string) decimal.Decimal {
+	d, _ := decimal.NewFromString(s)
+	return d
+}
+
 // parametersToValues takes a pointer to a Parameters struct, and converts the values
 // to url.Values which can be used in GET queries. Any field in the struct with a `cbt`
 // tag will have that tag used as the key in the url.Values.
@@ -50,6 +62,11 @@ func parametersToValues(p interface{}) (u url.Values, err error) {
 				if i := val.Int(); i != 0 {
 					u.Add(tag, fmt.Sprintf("%d", i))
 				}
+				// bools: only sent when true
+			case reflect.Bool:
+				if val.Bool() {
+					u.Add(tag, "true")
+				}
 				// slice of strings: add each separately
 			case reflect.Slice:
 				if val.Len() > 0 {
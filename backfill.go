@@ -0,0 +1,119 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultBackfillWorkers = 4
+
+type candleChunk struct {
+	candles []Candle
+	err     error
+}
+
+// BackfillCandles downloads candles for a long period by requesting several chunks at once, using
+// up to `workers` concurrent requests (4 if workers is zero or less). Requests still go through
+// the client's rate limiter, so they are shared fairly with anything else using the client.
+//
+// Candles are delivered on the returned channel oldest first, as soon as every earlier chunk has
+// arrived. If a request fails, or the context is cancelled, the error is sent on the error channel
+// and no more candles are delivered. Both channels are closed when the backfill is finished.
+//
+// If the client has a CandleStore, chunks it already has aren't downloaded again.
+func (c *Client) BackfillCandles(ctx context.Context, id string, start, end time.Time, granularity Granularity, workers int) (<-chan Candle, <-chan error) {
+	if workers <= 0 {
+		workers = defaultBackfillWorkers
+	}
+
+	out := make(chan Candle, maxCandlesPerRequest)
+	errc := make(chan error, 1)
+
+	chunks := candleChunks(start, end, granularity)
+	results := make([]chan candleChunk, len(chunks))
+	for i := range results {
+		results[i] = make(chan candleChunk, 1)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// limit how far the workers can get ahead of the consumer, so memory use stays bounded
+	window := make(chan struct{}, workers*2)
+	jobs := make(chan int)
+
+	go func() {
+		defer close(jobs)
+		for i := range chunks {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				candles, err := c.storedCandles(ctx, id, chunks[i][0], chunks[i][1], granularity)
+				results[i] <- candleChunk{candles, err}
+			}
+		}()
+	}
+
+	// deliver each chunk in order
+	go func() {
+		defer func() {
+			cancel()
+			wg.Wait()
+			close(out)
+			close(errc)
+		}()
+
+		var last int64
+		emitted := false
+		for i := range chunks {
+			var res candleChunk
+			select {
+			case res = <-results[i]:
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+			if res.err != nil {
+				errc <- res.err
+				return
+			}
+
+			// the api sends candles newest first
+			for j := len(res.candles) - 1; j >= 0; j-- {
+				v := res.candles[j]
+				if emitted && v.StartUnix <= last {
+					continue // chunks can overlap at the edges
+				}
+				last, emitted = v.StartUnix, true
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					errc <- ctx.Err()
+					return
+				}
+			}
+			<-window
+		}
+	}()
+
+	return out, errc
+}
@@ -0,0 +1,171 @@
+package coinbasetrade
+
+import (
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// BalanceChange is a change to the balance of a currency worked out from an order update.
+type BalanceChange struct {
+	Currency string
+	Previous decimal.Decimal
+	Current  decimal.Decimal
+	OrderID  string // the order whose fill caused the change
+}
+
+// BalanceTracker keeps the user's balances current from the user websocket channel, without
+// polling ListAccounts. The user channel doesn't report balances, so they are worked out from the
+// change in each order's filled size, average price, and fees: a buy adds the base currency and
+// takes the cost plus fees from the quote currency, and a sell does the opposite.
+//
+// Balances are totals (available plus held), since holds aren't reported either. Deposits,
+// withdrawals, and anything else that isn't a fill aren't seen, and fills that happen while the
+// websocket is disconnected are missed, so call Refresh now and then, and after a ResyncChannel
+// or GapChannel message (see Stale). It is safe to use from multiple goroutines.
+type BalanceTracker struct {
+	OnChange func(BalanceChange) // called for each changed balance, in the goroutine calling Apply
+
+	client   *Client
+	mu       sync.Mutex
+	balances map[string]decimal.Decimal
+	orders   map[string]orderProgress
+	stale    bool
+}
+
+// orderProgress is how much of an order had been filled at its last update
+type orderProgress struct {
+	filled decimal.Decimal
+	value  decimal.Decimal // filled size times average price
+	fees   decimal.Decimal
+}
+
+// NewBalanceTracker creates a BalanceTracker that uses this client to fetch the starting balances.
+func (c *Client) NewBalanceTracker(onChange func(BalanceChange)) *BalanceTracker {
+	return &BalanceTracker{
+		OnChange: onChange,
+		client:   c,
+		balances: make(map[string]decimal.Decimal),
+		orders:   make(map[string]orderProgress),
+	}
+}
+
+// Refresh replaces the balances with the totals of the user's accounts. OnChange isn't called.
+func (t *BalanceTracker) Refresh() error {
+	accounts, err := t.client.allAccounts()
+	if err != nil {
+		return err
+	}
+
+	balances := make(map[string]decimal.Decimal)
+	for _, a := range accounts {
+		balances[a.Currency] = balances[a.Currency].Add(a.AvailableBalance.Value).Add(a.HoldBalance.Value)
+	}
+
+	t.mu.Lock()
+	t.balances, t.stale = balances, false
+	t.mu.Unlock()
+	return nil
+}
+
+// Apply updates the balances from a user channel message. Snapshots only record how far each open
+// order has been filled, since those fills are already in the balances. A ResyncChannel or
+// GapChannel message marks the balances as stale. Other messages are ignored.
+func (t *BalanceTracker) Apply(msg WebsocketMessage) error {
+	if msg.Channel == ResyncChannel || msg.Channel == GapChannel {
+		t.mu.Lock()
+		t.stale = true
+		t.mu.Unlock()
+		return nil
+	}
+	if msg.Channel != UserChannel {
+		return nil
+	}
+
+	events, err := msg.UserEvents()
+	if err != nil {
+		return err
+	}
+
+	var changes []BalanceChange
+	t.mu.Lock()
+	for _, e := range events {
+		for _, o := range e.Orders {
+			progress := orderProgress{
+				filled: o.FilledSize,
+				value:  o.FilledSize.Mul(o.AverageFilledPrice),
+				fees:   o.TotalFees,
+			}
+			// an order that hasn't been seen before had nothing filled before this update
+			prev := t.orders[o.ID]
+			t.orders[o.ID] = progress
+			if o.Status.Terminal() {
+				delete(t.orders, o.ID)
+			}
+			if e.Type == "snapshot" {
+				continue
+			}
+
+			base, quote := splitProductID(o.Product)
+			size := progress.filled.Sub(prev.filled)
+			value := progress.value.Sub(prev.value)
+			fees := progress.fees.Sub(prev.fees)
+			if size.IsZero() && value.IsZero() && fees.IsZero() {
+				continue
+			}
+
+			if o.Side == Buy {
+				changes = t.add(changes, o.ID, base, size)
+				changes = t.add(changes, o.ID, quote, value.Add(fees).Neg())
+			} else {
+				changes = t.add(changes, o.ID, base, size.Neg())
+				changes = t.add(changes, o.ID, quote, value.Sub(fees))
+			}
+		}
+	}
+	t.mu.Unlock()
+
+	if t.OnChange != nil {
+		for _, c := range changes {
+			t.OnChange(c)
+		}
+	}
+	return nil
+}
+
+// add changes a balance, and records the change; t.mu must be held
+func (t *BalanceTracker) add(changes []BalanceChange, orderID, currency string, amount decimal.Decimal) []BalanceChange {
+	if amount.IsZero() {
+		return changes
+	}
+	prev := t.balances[currency]
+	t.balances[currency] = prev.Add(amount)
+	return append(changes, BalanceChange{Currency: currency, Previous: prev, Current: t.balances[currency], OrderID: orderID})
+}
+
+// Balance returns the current total balance of a currency.
+func (t *BalanceTracker) Balance(currency string) decimal.Decimal {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.balances[currency]
+}
+
+// Balances returns a copy of every balance, by currency.
+func (t *BalanceTracker) Balances() map[string]decimal.Decimal {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	balances := make(map[string]decimal.Decimal, len(t.balances))
+	for k, v := range t.balances {
+		balances[k] = v
+	}
+	return balances
+}
+
+// Stale reports whether the websocket has reconnected or missed messages since the last Refresh,
+// so fills may have been missed.
+func (t *BalanceTracker) Stale() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stale
+}
@@ -0,0 +1,109 @@
+package coinbasetrade
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+var bpsPerUnit = decimal.NewFromInt(10000)
+
+// DepthWithin returns the total size of the bids and asks priced within the given number of basis
+// points of the mid-market price, and false if either side of the book is empty.
+func (ob *OrderBook) DepthWithin(bps decimal.Decimal) (bidSize, askSize decimal.Decimal, ok bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	if len(ob.bids) == 0 || len(ob.asks) == 0 {
+		return
+	}
+
+	mid := ob.bids[0].Price.Add(ob.asks[0].Price).Div(decimal.NewFromInt(2))
+	band := mid.Mul(bps).Div(bpsPerUnit)
+	low, high := mid.Sub(band), mid.Add(band)
+
+	for _, l := range ob.bids {
+		if l.Price.LessThan(low) {
+			break
+		}
+		bidSize = bidSize.Add(l.Size)
+	}
+	for _, l := range ob.asks {
+		if l.Price.GreaterThan(high) {
+			break
+		}
+		askSize = askSize.Add(l.Size)
+	}
+	return bidSize, askSize, true
+}
+
+// Imbalance returns how lopsided the top n levels of the book are (every level if n is zero or
+// less): the bid size minus the ask size, divided by their total. It ranges from -1 (only asks) to
+// 1 (only bids), and is false if the book is empty.
+func (ob *OrderBook) Imbalance(n int) (imbalance decimal.Decimal, ok bool) {
+	bids, asks := ob.Depth(n)
+
+	var bidSize, askSize decimal.Decimal
+	for _, l := range bids {
+		bidSize = bidSize.Add(l.Size)
+	}
+	for _, l := range asks {
+		askSize = askSize.Add(l.Size)
+	}
+
+	total := bidSize.Add(askSize)
+	if !total.IsPositive() {
+		return
+	}
+	return bidSize.Sub(askSize).Div(total), true
+}
+
+// MarketImpact is what a market order would get if it were filled from the book as it stands.
+type MarketImpact struct {
+	Filled       decimal.Decimal // the base size that could be filled; less than asked for if the book ran out
+	Cost         decimal.Decimal // the quote value of the fills, before fees
+	AveragePrice decimal.Decimal
+	WorstPrice   decimal.Decimal // the price of the last level reached
+	SlippageBps  decimal.Decimal // how far the average price is from the best price, in basis points
+	Complete     bool            // whether the whole size could be filled
+}
+
+// MarketImpact works out the fills a market order of the given base size would get by walking the
+// asks (for a buy) or bids (for a sell). Fees aren't included, and the order isn't placed.
+func (ob *OrderBook) MarketImpact(side Side, size decimal.Decimal) MarketImpact {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	if side == Buy {
+		return walkLevels(ob.asks, size)
+	}
+	return walkLevels(ob.bids, size)
+}
+
+// MarketImpact works out the fills a market order of the given base size would get from this
+// snapshot of the book. See OrderBook.MarketImpact.
+func (b ProductBook) MarketImpact(side Side, size decimal.Decimal) MarketImpact {
+	if side == Buy {
+		return walkLevels(b.Asks, size)
+	}
+	return walkLevels(b.Bids, size)
+}
+
+// walkLevels fills a size from sorted levels, best price first
+func walkLevels(levels []BookLevel, size decimal.Decimal) (impact MarketImpact) {
+	remaining := size
+	for _, l := range levels {
+		if !remaining.IsPositive() {
+			break
+		}
+		fill := decimal.Min(remaining, l.Size)
+		impact.Filled = impact.Filled.Add(fill)
+		impact.Cost = impact.Cost.Add(fill.Mul(l.Price))
+		impact.WorstPrice = l.Price
+		remaining = remaining.Sub(fill)
+	}
+	impact.Complete = !remaining.IsPositive()
+
+	if impact.Filled.IsPositive() {
+		impact.AveragePrice = impact.Cost.Div(impact.Filled)
+		best := levels[0].Price
+		impact.SlippageBps = impact.AveragePrice.Sub(best).Abs().Div(best).Mul(bpsPerUnit)
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrBookChecksum is returned when a saved order book doesn't match its checksum.
+var ErrBookChecksum = errors.New("order book snapshot doesn't match its checksum")
+
+// BookSnapshot is a copy of an OrderBook that can be saved and reloaded, e.g. to checkpoint it,
+// share it with another process, or look at it after an incident.
+type BookSnapshot struct {
+	ProductID string      `json:"product_id"`
+	Bids      []BookLevel `json:"bids"` // highest price first
+	Asks      []BookLevel `json:"asks"` // lowest price first
+	Sequence  int64       `json:"sequence"`
+	Synced    bool        `json:"synced"` // whether the book was in sync with the feed when the snapshot was taken
+	Time      time.Time   `json:"time"`
+	Checksum  uint32      `json:"checksum"`
+}
+
+// Snapshot returns a copy of the book.
+func (ob *OrderBook) Snapshot() BookSnapshot {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	s := BookSnapshot{
+		ProductID: ob.ProductID,
+		Bids:      topLevels(ob.bids, 0),
+		Asks:      topLevels(ob.asks, 0),
+		Sequence:  ob.lastSeq,
+		Synced:    ob.synced && ob.err == nil,
+		Time:      time.Now(),
+	}
+	s.Checksum = checksumLevels(s.Bids, s.Asks)
+	return s
+}
+
+// Restore replaces the book's levels with those of a snapshot, after checking its checksum. The
+// levels can be read straight away, but the book isn't Synced until a new snapshot arrives from
+// the feed, since updates can't be applied on top of a saved book.
+func (ob *OrderBook) Restore(s BookSnapshot) error {
+	if s.Checksum != checksumLevels(s.Bids, s.Asks) {
+		return ErrBookChecksum
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.ProductID = s.ProductID
+	ob.bids, ob.asks = topLevels(s.Bids, 0), topLevels(s.Asks, 0)
+	ob.haveSeq, ob.synced, ob.err = false, false, nil
+	return nil
+}
+
+// Save writes a snapshot of the book to w as JSON.
+func (ob *OrderBook) Save(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(ob.Snapshot()); err != nil {
+		return formatError("save order book", err)
+	}
+	return nil
+}
+
+// LoadOrderBook reads a book written by Save. See Restore.
+func LoadOrderBook(r io.Reader) (*OrderBook, error) {
+	var s BookSnapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, formatError("load order book", err)
+	}
+
+	ob := NewOrderBook(s.ProductID)
+	if err := ob.Restore(s); err != nil {
+		return nil, formatError("load order book", fmt.Errorf("%w (product %s)", err, s.ProductID))
+	}
+	return ob, nil
+}
@@ -0,0 +1,178 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MarketTradesChannel sends every trade made for the subscribed products.
+const MarketTradesChannel Channel = "market_trades"
+
+// MarketTradeEvent is a snapshot of recent trades, or an update with new ones.
+type MarketTradeEvent struct {
+	Type   string  `json:"type"` // "snapshot" or "update"
+	Trades []Trade `json:"trades"`
+}
+
+// MarketTradeEvents decodes the events from a market_trades channel message.
+func (m WebsocketMessage) MarketTradeEvents() (events []MarketTradeEvent, err error) {
+	if m.Channel != MarketTradesChannel {
+		err = fmt.Errorf("cannot decode %s message as market trade events", m.Channel)
+		return
+	}
+
+	if err = json.Unmarshal(m.Events, &events); err != nil {
+		err = formatError("unmarshal market trade events", err)
+	}
+	return
+}
+
+// CandleBuilder builds candles of any length from individual trades, either from the
+// market_trades websocket channel or from polling GetMarketTrades. It is safe to use from multiple
+// goroutines.
+type CandleBuilder struct {
+	Period  time.Duration
+	OnClose func(productID string, c Candle) // called with each candle once its period has ended
+
+	mu       sync.Mutex
+	products map[string]*builderState
+}
+
+type builderState struct {
+	candle   Candle
+	lastTime time.Time       // the time of the newest trade added
+	lastIDs  map[string]bool // ids of trades at lastTime, to skip duplicates when polling
+	closed   time.Time       // the start of the newest candle closed, whose period takes no more trades
+}
+
+// NewCandleBuilder creates a builder for candles of the given period. Periods are aligned to whole
+// multiples of the period in UTC.
+func NewCandleBuilder(period time.Duration, onClose func(productID string, c Candle)) *CandleBuilder {
+	return &CandleBuilder{
+		Period:   period,
+		OnClose:  onClose,
+		products: make(map[string]*builderState),
+	}
+}
+
+// Apply adds the trades from a websocket message. Messages from other channels are ignored.
+func (b *CandleBuilder) Apply(msg WebsocketMessage) (err error) {
+	if msg.Channel != MarketTradesChannel {
+		return
+	}
+
+	var events []MarketTradeEvent
+	if events, err = msg.MarketTradeEvents(); err != nil {
+		return
+	}
+
+	for _, e := range events {
+		// trades are sent newest first
+		for i := len(e.Trades) - 1; i >= 0; i-- {
+			b.AddTrade(e.Trades[i])
+		}
+	}
+	return
+}
+
+// AddTrade adds one trade. Trades older than the newest one already added for the product, trades
+// that have already been added, and trades in the period of a candle that has already been closed
+// (e.g. by Flush) are ignored, so the results of GetMarketTrades can be passed in repeatedly
+// (oldest first).
+func (b *CandleBuilder) AddTrade(t Trade) {
+	var closed *Candle
+
+	b.mu.Lock()
+	s, ok := b.products[t.ProductID]
+	if !ok {
+		s = &builderState{lastIDs: make(map[string]bool)}
+		b.products[t.ProductID] = s
+	}
+
+	start := t.Time.UTC().Truncate(b.Period)
+	switch {
+	case t.Time.Before(s.lastTime), s.lastIDs[t.ID], !s.closed.IsZero() && !start.After(s.closed):
+		b.mu.Unlock()
+		return
+	case t.Time.After(s.lastTime):
+		s.lastTime = t.Time
+		s.lastIDs = make(map[string]bool)
+	}
+	s.lastIDs[t.ID] = true
+
+	building := !s.candle.StartTime.IsZero()
+	switch {
+	case building && start.Equal(s.candle.StartTime):
+		c := &s.candle
+		if t.Price.GreaterThan(c.High) {
+			c.High = t.Price
+		}
+		if t.Price.LessThan(c.Low) {
+			c.Low = t.Price
+		}
+		c.Close = t.Price
+		c.Volume = c.Volume.Add(t.Size)
+		b.mu.Unlock()
+		return
+	case building:
+		prev := s.candle
+		closed = &prev
+		s.closed = prev.StartTime
+	}
+
+	s.candle = Candle{
+		StartString: strconv.FormatInt(start.Unix(), 10),
+		StartTime:   start,
+		StartUnix:   start.Unix(),
+		Low:         t.Price,
+		High:        t.Price,
+		Open:        t.Price,
+		Close:       t.Price,
+		Volume:      t.Size,
+	}
+	b.mu.Unlock()
+
+	if closed != nil && b.OnClose != nil {
+		b.OnClose(t.ProductID, *closed)
+	}
+}
+
+// Flush closes any candles whose period ended before now, for products that have had no trades
+// since. Call this periodically if candles should be closed on time even when trading is slow.
+func (b *CandleBuilder) Flush(now time.Time) {
+	type closedCandle struct {
+		product string
+		candle  Candle
+	}
+	var closed []closedCandle
+
+	b.mu.Lock()
+	for product, s := range b.products {
+		if !s.candle.StartTime.IsZero() && !s.candle.StartTime.Add(b.Period).After(now) {
+			closed = append(closed, closedCandle{product, s.candle})
+			s.closed = s.candle.StartTime
+			s.candle = Candle{}
+		}
+	}
+	b.mu.Unlock()
+
+	if b.OnClose != nil {
+		for _, c := range closed {
+			b.OnClose(c.product, c.candle)
+		}
+	}
+}
+
+// Current returns the candle being built for a product, and false if there isn't one.
+func (b *CandleBuilder) Current(productID string) (c Candle, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, found := b.products[productID]
+	if !found || s.candle.StartTime.IsZero() {
+		return
+	}
+	return s.candle, true
+}
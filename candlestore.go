@@ -0,0 +1,235 @@
+package coinbasetrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CandleStore saves downloaded candles, so history doesn't have to be downloaded again. When a
+// Client has a store, BackfillCandles gets each chunk of candles from the store if the store has
+// all of it, and otherwise downloads the chunk and saves it. Chunks that include the current
+// candle aren't saved, since it hasn't closed yet.
+//
+// A period with no trades has no candles, so the store keeps track of which ranges it has been
+// given, as well as the candles in them.
+type CandleStore interface {
+	// Put saves the candles for a range of time, replacing any already saved with the same start.
+	Put(productID string, granularity Granularity, start, end time.Time, candles []Candle) error
+
+	// Get returns the saved candles starting within a range of time, oldest first. Complete is
+	// true if the whole range has been saved before.
+	Get(productID string, granularity Granularity, start, end time.Time) (candles []Candle, complete bool, err error)
+}
+
+// candleSpan is a range of candle start times, in unix seconds
+type candleSpan struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// candleSeries is the saved candles of one product and granularity
+type candleSeries struct {
+	Candles []Candle     `json:"candles"` // oldest first
+	Covered []candleSpan `json:"covered"` // sorted and not overlapping
+}
+
+// put adds candles and the range they cover
+func (s *candleSeries) put(granularity Granularity, start, end time.Time, candles []Candle) {
+	byStart := make(map[int64]Candle, len(s.Candles)+len(candles))
+	for _, c := range s.Candles {
+		byStart[c.StartUnix] = c
+	}
+	for _, c := range candles {
+		byStart[c.StartUnix] = c
+	}
+	s.Candles = s.Candles[:0]
+	for _, c := range byStart {
+		s.Candles = append(s.Candles, c)
+	}
+	sort.Slice(s.Candles, func(i, j int) bool { return s.Candles[i].StartUnix < s.Candles[j].StartUnix })
+
+	// merge the new span with any it overlaps or touches
+	spans := append(s.Covered, candleSpan{start.Unix(), end.Unix()})
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	step := int64(granularity.Duration() / time.Second)
+	s.Covered = nil
+	for _, sp := range spans {
+		if n := len(s.Covered); n > 0 && sp.Start <= s.Covered[n-1].End+step {
+			if sp.End > s.Covered[n-1].End {
+				s.Covered[n-1].End = sp.End
+			}
+			continue
+		}
+		s.Covered = append(s.Covered, sp)
+	}
+}
+
+// get returns the candles in a range, and whether the range is covered
+func (s *candleSeries) get(start, end time.Time) (candles []Candle, complete bool) {
+	from, to := start.Unix(), end.Unix()
+	for _, c := range s.Candles {
+		if c.StartUnix >= from && c.StartUnix <= to {
+			candles = append(candles, c)
+		}
+	}
+	for _, sp := range s.Covered {
+		if sp.Start <= from && sp.End >= to {
+			complete = true
+			break
+		}
+	}
+	return
+}
+
+// MemoryCandleStore is a CandleStore that doesn't persist anything. It avoids downloading the same
+// candles twice within one run of a program.
+type MemoryCandleStore struct {
+	mu     sync.Mutex
+	series map[string]*candleSeries
+}
+
+func (m *MemoryCandleStore) Put(productID string, granularity Granularity, start, end time.Time, candles []Candle) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.series == nil {
+		m.series = make(map[string]*candleSeries)
+	}
+
+	key := productID + "/" + string(granularity)
+	if m.series[key] == nil {
+		m.series[key] = &candleSeries{}
+	}
+	m.series[key].put(granularity, start, end, candles)
+	return nil
+}
+
+func (m *MemoryCandleStore) Get(productID string, granularity Granularity, start, end time.Time) (candles []Candle, complete bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.series[productID+"/"+string(granularity)]; s != nil {
+		candles, complete = s.get(start, end)
+	}
+	return
+}
+
+// FileCandleStore is a CandleStore that keeps the candles of each product and granularity in a
+// JSON file in a directory, e.g. "BTC-USD_ONE_HOUR.json". Each file is read and rewritten whole,
+// so it suits history of up to a few hundred thousand candles per file.
+type FileCandleStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewFileCandleStore creates a store in a directory, creating the directory if needed.
+func NewFileCandleStore(dir string) (*FileCandleStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, formatError("create candle store", err)
+	}
+	return &FileCandleStore{Dir: dir}, nil
+}
+
+// path returns the file for a product and granularity
+func (f *FileCandleStore) path(productID string, granularity Granularity) string {
+	name := strings.NewReplacer("/", "_", `\`, "_").Replace(productID + "_" + string(granularity))
+	return filepath.Join(f.Dir, name+".json")
+}
+
+// load reads a file, which is empty if it doesn't exist yet
+func (f *FileCandleStore) load(path string) (s candleSeries, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &s)
+	}
+	return
+}
+
+func (f *FileCandleStore) Put(productID string, granularity Granularity, start, end time.Time, candles []Candle) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.path(productID, granularity)
+	s, err := f.load(path)
+	if err != nil {
+		return formatError("read candle store", err)
+	}
+	s.put(granularity, start, end, candles)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return formatError("write candle store", err)
+	}
+
+	if err = writeFileAtomic(path, data); err != nil {
+		return formatError("write candle store", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file first and then renames it, so a crash can't leave a
+// half written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (f *FileCandleStore) Get(productID string, granularity Granularity, start, end time.Time) (candles []Candle, complete bool, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var s candleSeries
+	if s, err = f.load(f.path(productID, granularity)); err != nil {
+		err = formatError("read candle store", err)
+		return
+	}
+	candles, complete = s.get(start, end)
+	return
+}
+
+// storedCandles gets candles for one chunk from the client's CandleStore if it has all of them,
+// and otherwise downloads them and saves them to the store. Like getCandles, they are returned
+// newest first. Problems with the store are logged rather than returned, since the candles can
+// still be downloaded.
+func (c *Client) storedCandles(ctx context.Context, id string, start, end time.Time, granularity Granularity) (candles []Candle, err error) {
+	if c.CandleStore == nil {
+		return c.getCandles(ctx, id, start, end, granularity)
+	}
+
+	stored, complete, err := c.CandleStore.Get(id, granularity, start, end)
+	if err != nil {
+		c.log(slog.LevelWarn, "could not read candle store", "product", id, "error", err)
+	}
+	if err == nil && complete {
+		for i := len(stored) - 1; i >= 0; i-- {
+			candles = append(candles, stored[i])
+		}
+		return
+	}
+
+	if candles, err = c.getCandles(ctx, id, start, end, granularity); err != nil {
+		return
+	}
+
+	// the last candle of a chunk that reaches the present is still changing
+	if end.Add(granularity.Duration()).Before(c.now()) {
+		if perr := c.CandleStore.Put(id, granularity, start, end, candles); perr != nil {
+			c.log(slog.LevelWarn, "could not save to candle store", "product", id, "error", perr)
+		}
+	}
+	return
+}
@@ -2,62 +2,209 @@ package coinbasetrade
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Method string
 
+// Base URLs for the API. DefaultHost is used unless another host is set; CDP keys are only
+// accepted by CloudHost, so it is used for them instead. SandboxHost serves static responses for
+// the account and order endpoints, and doesn't need credentials.
 const (
-	apiInterval = time.Millisecond * 50 // the minimum amount of time to wait in between API calls
-	apiTimeout  = time.Second * 60      // how long to wait for a response
+	DefaultHost = "https://coinbase.com"
+	CloudHost   = "https://api.coinbase.com"
+	SandboxHost = "https://api-sandbox.coinbase.com"
+	DefaultPath = "/api/v3/brokerage"
+)
+
+const (
+	apiRate     = 20               // average requests per second allowed by the default limiter
+	apiBurst    = 10               // requests that can be made at once by the default limiter
+	publicRate  = 10               // average requests per second allowed by the default public limiter
+	publicBurst = 10               // requests that can be made at once by the default public limiter
+	apiTimeout  = time.Second * 60 // how long to wait for a response
 
 	Get    Method = "GET"
 	Put    Method = "PUT"
 	Post   Method = "POST"
 	Delete Method = "DELETE"
 
-	listAccountsEndpoint          = "/accounts"
-	getAccountEndpoint            = "/accounts/%s"
-	createOrderEndpoint           = "/orders"
-	cancelOrdersEndpoint          = "/orders/batch_cancel"
-	listOrdersEndpoint            = "/orders/historical/batch"
-	listFillsEndpoint             = "/orders/historical/fills"
-	getOrderEndpoint              = "/orders/historical/%s"
-	listProductsEndpoint          = "/products"
-	getProductEndpoint            = "/products/%s"
-	getProductCandlesEndpoint     = "/products/%s/candles"
-	getMarketTradesEndpoint       = "/products/%s/ticker"
+	listAccountsEndpoint      = "/accounts"
+	getAccountEndpoint        = "/accounts/%s"
+	createOrderEndpoint       = "/orders"
+	previewOrderEndpoint      = "/orders/preview"
+	cancelOrdersEndpoint      = "/orders/batch_cancel"
+	listOrdersEndpoint        = "/orders/historical/batch"
+	listFillsEndpoint         = "/orders/historical/fills"
+	getOrderEndpoint          = "/orders/historical/%s"
+	listProductsEndpoint      = "/products"
+	getProductEndpoint        = "/products/%s"
+	getProductCandlesEndpoint = "/products/%s/candles"
+	getMarketTradesEndpoint   = "/products/%s/ticker"
+	getProductBookEndpoint    = "/product_book"
+
+	// market data endpoints that don't require authentication are the same as the ones above, with this prefix
+	publicMarketPrefix            = "/market"
 	getTransactionSummaryEndpoint = "/transaction_summary"
+	movePortfolioFundsEndpoint    = "/portfolios/move_funds"
+	getServerTimeEndpoint         = "/time"
+	getKeyPermissionsEndpoint     = "/key_permissions"
 )
 
 type Client struct {
-	Host     string // i.e. coinbase.com
-	Path     string // path to the api
-	Key      string // API key as provided by Coinbase
-	Secret   string // API secret as provided by Coinbase
-	lastCall time.Time
-	client   *http.Client
-
-	debug bool
+	Host          string      // i.e. coinbase.com
+	Path          string      // path to the api
+	Key           string      // API key as provided by Coinbase
+	Secret        string      // API secret as provided by Coinbase
+	Retry         RetryPolicy // how failed requests are retried
+	Poll          PollPolicy  // how often helpers like WaitForFill check on an order
+	Limiter       Limiter     // controls how often requests can be made
+	PublicLimiter Limiter     // controls how often requests to the public market data endpoints can be made
+
+	// creates the client order id for orders placed without one (UUIDGenerator by default)
+	ClientOrderIDs ClientOrderIDGenerator
+
+	// if set, order submissions are recorded so an order is never sent twice (see IdempotencyStore)
+	Submissions        IdempotencyStore
+	client             *http.Client
+	products           *productCache
+	accountIDs         *accountIDCache
+	rateLimit          *rateLimitState
+	publicRateLimit    *rateLimitState
+	limiterStats       *limiterStats
+	publicLimiterStats *limiterStats
+	clock              *clockOffset
+
+	// Coinbase Cloud (CDP) keys are used instead of Key and Secret when PrivateKey is set
+	KeyName    string // i.e. organizations/{org_id}/apiKeys/{key_id}
+	PrivateKey string // PEM encoded EC private key
+	parsedKey  *parsedKey
+
+	// more keys to spread requests across, along with the one above. They should be the same kind
+	// of key, since CDP keys need a different host. Websockets only use the key above.
+	Keys        []APIKey
+	KeyRotation KeyRotation
+	keyRotation *keyRotationState
+
+	// if true, orders are checked against the product's increments and size limits before they
+	// are sent, using product details that are cached for an hour
+	ValidateOrders bool
+
+	// if true, order sizes are rounded down to the product's increments before they are sent, and
+	// prices are rounded to the price increment using PriceRounding
+	RoundOrders   bool
+	PriceRounding RoundingMode
+
+	// if set, requests, responses, retries, and rate limit waits are logged to it. Requests and
+	// responses are logged at debug level, retries and websocket reconnects at warn level.
+	Logger *slog.Logger
+
+	// if set, request counts, latencies, rate limit waits, retries, and websocket reconnects are
+	// reported to it
+	Metrics Metrics
+
+	// if set, a span is created for each request and each websocket message
+	Tracer Tracer
+
+	// if set, every request and response is written to it in full, with secrets redacted
+	Dump io.Writer
+
+	// if true, accounts, orders, fills, and products keep the JSON they were decoded from in their
+	// Raw field, for fields this package doesn't have yet or to archive exact responses
+	KeepRaw bool
+
+	// if true, gzip compressed responses aren't asked for
+	DisableCompression bool
+
+	// if set, called after every attempt at a request with its status, latency, and request id
+	OnResponse func(ResponseInfo)
+
+	// if set, BackfillCandles reads candles from it when it has them, and saves the ones it downloads
+	CandleStore CandleStore
+
+	public           bool // if true, requests aren't signed and market data comes from the public endpoints
+	publicMarketData bool // if true, market data comes from the public endpoints
+	sandbox          bool // if true, requests aren't signed
+
+	transportOptions *TransportOptions // the proxy and dialer are also used for websockets
 }
 
 type ClientConfig struct {
-	Host   string
-	Path   string
-	Key    string
-	Secret string
+	Host           string
+	Path           string
+	Key            string
+	Secret         string
+	KeyName        string
+	PrivateKey     string
+	Keys           []APIKey     // optional, more keys to spread requests across
+	KeyRotation    KeyRotation  // optional, when to move on to the next key (RotateOnRateLimit by default)
+	Retry          *RetryPolicy // optional, DefaultRetryPolicy is used if nil
+	Poll           *PollPolicy  // optional, DefaultPollPolicy is used if nil
+	ValidateOrders bool         // optional, check orders against product details before sending them
+	RoundOrders    bool         // optional, round orders to product increments before sending them
+	PriceRounding  RoundingMode // optional, how prices are rounded when RoundOrders is set
+	Limiter        Limiter      // optional, a TokenBucket is used if nil
+	PublicLimiter  Limiter      // optional, a separate TokenBucket for the public endpoints is used if nil
+
+	ClientOrderIDs ClientOrderIDGenerator // optional, UUIDGenerator is used if nil
+	Submissions    IdempotencyStore       // optional, records order submissions to prevent duplicates
+
+	// Optional: the http client used to make requests. If nil, one is created with a 60 second
+	// timeout that uses Transport (or http.DefaultTransport if Transport is also nil).
+	HTTPClient *http.Client
+	Transport  http.RoundTripper
+
+	// Optional: connection pool, proxy, and dialer settings, used to create the transport if
+	// HTTPClient and Transport are both nil. The proxy and dialer are also used by websockets.
+	TransportOptions *TransportOptions
+
+	// Optional: use the sandbox (SandboxHost, unless Host is set) instead of the production
+	// server. Credentials aren't needed.
+	Sandbox bool
+
+	// Optional: get market data (products, candles, trades, and books) from the public endpoints,
+	// which have their own rate limit, so polling market data doesn't slow down trading. Public
+	// data may be slightly delayed.
+	PublicMarketData bool
+
+	// Optional: where to log requests, responses, and retries. Nothing is logged if nil.
+	Logger *slog.Logger
+
+	// Optional: where to report request and websocket metrics.
+	Metrics Metrics
+
+	// Optional: creates spans around requests and websocket messages, for distributed tracing.
+	Tracer Tracer
+
+	// Optional: where to dump every request and response, with secrets redacted.
+	Dump io.Writer
+
+	// Optional: keep the JSON of accounts, orders, fills, and products in their Raw field.
+	KeepRaw bool
+
+	// Optional: don't ask for gzip compressed responses.
+	DisableCompression bool
+
+	// Optional: called after every attempt at a request, with its status, latency, and request id.
+	OnResponse func(ResponseInfo)
+
+	// Optional: where BackfillCandles keeps downloaded candles, so they aren't downloaded again.
+	CandleStore CandleStore
 }
 
 func NewClient(config *ClientConfig) *Client {
@@ -68,12 +215,15 @@ func NewClient(config *ClientConfig) *Client {
 			Path:   config.Path,
 			Key:    config.Key,
 			Secret: config.Secret,
+
+			KeyName:    config.KeyName,
+			PrivateKey: config.PrivateKey,
 		}
 	}
 
 	defaults := Client{
-		Host: "https://coinbase.com",
-		Path: "/api/v3/brokerage",
+		Host: DefaultHost,
+		Path: DefaultPath,
 	}
 
 	c := &Client{
@@ -81,6 +231,9 @@ func NewClient(config *ClientConfig) *Client {
 		Path:   os.Getenv("COINBASE_PATH"),
 		Key:    os.Getenv("COINBASE_KEY"),
 		Secret: os.Getenv("COINBASE_SECRET"),
+
+		KeyName:    os.Getenv("COINBASE_KEY_NAME"),
+		PrivateKey: os.Getenv("COINBASE_PRIVATE_KEY"),
 	}
 
 	for _, v := range []Client{cc, defaults} {
@@ -96,58 +249,104 @@ func NewClient(config *ClientConfig) *Client {
 		if c.Secret == "" {
 			c.Secret = v.Secret
 		}
+		if c.KeyName == "" {
+			c.KeyName = v.KeyName
+		}
+		if c.PrivateKey == "" {
+			c.PrivateKey = v.PrivateKey
+		}
+	}
+
+	// CDP keys are only accepted by the api subdomain, and the host is part of the signed token
+	hostSet := os.Getenv("COINBASE_HOST") != "" || (config != nil && config.Host != "")
+	if c.usesJWT() && !hostSet {
+		c.Host = CloudHost
+	}
+
+	// the sandbox doesn't check credentials, so requests to it aren't signed
+	if config != nil && config.Sandbox {
+		c.sandbox = true
+		if !hostSet {
+			c.Host = SandboxHost
+		}
+	}
+
+	c.rateLimit = &rateLimitState{}
+	c.publicRateLimit = &rateLimitState{}
+	c.limiterStats = &limiterStats{}
+	c.publicLimiterStats = &limiterStats{}
+	c.clock = &clockOffset{}
+	c.parsedKey = &parsedKey{}
+	c.keyRotation = &keyRotationState{}
+	c.products = &productCache{}
+	c.accountIDs = &accountIDCache{}
+	if config != nil {
+		c.ValidateOrders = config.ValidateOrders
+		c.RoundOrders = config.RoundOrders
+		c.PriceRounding = config.PriceRounding
+		c.Submissions = config.Submissions
+		c.Keys = config.Keys
+		c.KeyRotation = config.KeyRotation
+		c.Logger = config.Logger
+		c.Metrics = config.Metrics
+		c.Tracer = config.Tracer
+		c.Dump = config.Dump
+		c.KeepRaw = config.KeepRaw
+		c.DisableCompression = config.DisableCompression
+		c.OnResponse = config.OnResponse
+		c.CandleStore = config.CandleStore
+	}
+	c.Retry = DefaultRetryPolicy
+	if config != nil && config.Retry != nil {
+		c.Retry = *config.Retry
+	}
+	c.Poll = DefaultPollPolicy
+	if config != nil && config.Poll != nil {
+		c.Poll = *config.Poll
 	}
 
 	c.client = &http.Client{
 		Timeout: apiTimeout,
 	}
-	c.lastCall = time.Now()
+	if config != nil {
+		if config.HTTPClient != nil {
+			c.client = config.HTTPClient
+		} else if config.Transport != nil {
+			c.client.Transport = config.Transport
+		} else if config.TransportOptions != nil {
+			c.client.Transport = config.TransportOptions.transport()
+		}
+		c.transportOptions = config.TransportOptions
+	}
+	c.Limiter = NewTokenBucket(apiRate, apiBurst)
+	if config != nil && config.Limiter != nil {
+		c.Limiter = config.Limiter
+	}
+	c.PublicLimiter = NewTokenBucket(publicRate, publicBurst)
+	if config != nil && config.PublicLimiter != nil {
+		c.PublicLimiter = config.PublicLimiter
+	}
+	if config != nil {
+		c.publicMarketData = config.PublicMarketData
+	}
+	c.ClientOrderIDs = UUIDGenerator{}
+	if config != nil && config.ClientOrderIDs != nil {
+		c.ClientOrderIDs = config.ClientOrderIDs
+	}
 	return c
 }
 
 // makeRequest is a convenience function that makes a request, unmarshals the response into any
 // provided interfaces, and also returns the raw response in case you need to do something else
-func (c *Client) makeRequest(m Method, endpoint string, query url.Values, payload []byte, result, pagination interface{}) (data []byte, err error) {
-
-	// ensure we observe the minimum interval time
-	time.Sleep(time.Until(c.lastCall.Add(apiInterval)))
-
-	var res *http.Response
-	if data, res, err = c.request(m, endpoint, query, payload); err != nil {
-		return
-	}
-
-	// if we don't get a success code
-	if res.StatusCode != 200 {
-
-		if c.debug {
-			log.Printf("Error response: %s", data)
-		}
-
-		// attempt to unmarshal error
-		e := struct {
-			Message string `json:"message"`
-		}{}
-		if err = json.Unmarshal(data, &e); err != nil {
-			// otherwise, return the body as the error
-			e.Message = fmt.Sprintf("(%d) %s", res.StatusCode, data)
-		}
-
-		// if the api key or secret is missing, include that info to help debug
-		if c.Key == "" || c.Secret == "" {
-			e.Message += " [API key or secret is missing]"
-		}
-
-		err = formatError("api response", errors.New(e.Message))
+func (c *Client) makeRequest(ctx context.Context, m Method, endpoint string, query url.Values, payload []byte, result, pagination interface{}) (data []byte, err error) {
+	if data, _, err = c.send(ctx, m, endpoint, query, payload); err != nil {
 		return
 	}
 
 	// if an interface was passed, try to unmarshal the response
 	if result != nil {
 		if err = json.Unmarshal(data, result); err != nil {
-			if c.debug {
-				log.Printf("API response causing error: %s\n", data)
-			}
+			c.log(slog.LevelError, "could not decode response", "method", m, "endpoint", endpoint, "error", err, "body", string(data))
 
 			err = formatError("unmarshal api result", err)
 			return
@@ -165,14 +364,121 @@ func (c *Client) makeRequest(m Method, endpoint string, query url.Values, payloa
 	return
 }
 
+// send makes a request, waiting for the limiter and retrying as needed, and returns an error if
+// the final response isn't a success
+func (c *Client) send(ctx context.Context, m Method, endpoint string, query url.Values, payload []byte) (data []byte, res *http.Response, err error) {
+	var pattern string
+	if c.Metrics != nil || c.Tracer != nil {
+		pattern = endpointPattern(endpoint)
+	}
+
+	var status, attempt int
+	if c.Tracer != nil {
+		var end func(status, attempts int, err error)
+		ctx, end = c.Tracer.StartRequest(ctx, m, pattern)
+		defer func() { end(status, attempt, err) }()
+	}
+
+	for attempt = 1; ; attempt++ {
+		// wait for our turn, so we don't exceed the rate limit
+		limiter, stats := c.limiter(endpoint)
+		waitStart := time.Now()
+		if err = limiter.Wait(ctx); err != nil {
+			err = formatError("rate limiter", err)
+			return
+		}
+		waited := time.Since(waitStart)
+		stats.record(waited)
+		if waited >= time.Millisecond {
+			c.log(slog.LevelDebug, "waited for rate limiter", "endpoint", endpoint, "wait", waited)
+		}
+		if c.Metrics != nil {
+			c.Metrics.ObserveRateLimitWait(pattern, waited)
+		}
+
+		c.log(slog.LevelDebug, "request", "method", m, "endpoint", endpoint, "query", query.Encode(), "attempt", attempt)
+		start := time.Now()
+		data, res, err = c.request(ctx, m, endpoint, query, payload)
+		latency := time.Since(start)
+		info := ResponseInfo{Method: m, Endpoint: endpoint, Attempt: attempt, Latency: latency, Err: err}
+		status = 0
+		if res != nil {
+			status = res.StatusCode
+			info.StatusCode, info.Header, info.RequestID = status, res.Header, requestID(res.Header)
+			c.updateRateLimit(endpoint, res.Header)
+			c.log(slog.LevelDebug, "response", "method", m, "endpoint", endpoint, "status", status, "duration", latency, "request_id", info.RequestID)
+		}
+		if c.Metrics != nil {
+			c.Metrics.ObserveRequest(m, pattern, status, latency)
+		}
+		if c.OnResponse != nil {
+			c.OnResponse(info)
+		}
+		if !c.Retry.shouldRetry(m, attempt, res, err) {
+			break
+		}
+
+		// if we are being rate limited, the server may tell us how long to wait, unless there is
+		// another key to use
+		wait := c.Retry.backoff(attempt)
+		if res != nil && res.StatusCode == http.StatusTooManyRequests {
+			if c.rotateKey() {
+				wait = 0
+			} else if d, ok := retryAfter(res.Header); ok {
+				wait = d
+			}
+		}
+		if c.Logger != nil {
+			reason := err
+			if reason == nil {
+				reason = fmt.Errorf("status %d", res.StatusCode)
+			}
+			c.log(slog.LevelWarn, "retrying request", "method", m, "endpoint", endpoint, "wait", wait, "attempt", attempt, "error", reason)
+		}
+		if c.Metrics != nil {
+			c.Metrics.ObserveRetry(m, pattern, attempt)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			err = formatError("retry", ctx.Err())
+			return
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// if we don't get a success code
+	if res.StatusCode != 200 {
+
+		c.log(slog.LevelDebug, "error response", "method", m, "endpoint", endpoint, "status", res.StatusCode, "body", string(data))
+
+		e := newAPIError(res, data)
+
+		// if the api key or secret is missing, include that info to help debug
+		switch {
+		case c.public, c.sandbox, isPublicEndpoint(endpoint): // no credentials needed
+		case c.usesJWT() && c.KeyName == "":
+			e.hint = "API key name is missing"
+		case !c.usesJWT() && (c.Key == "" || c.Secret == ""):
+			e.hint = "API key or secret is missing"
+		}
+
+		err = formatError("api response", e)
+		return
+	}
+	return
+}
+
 // request just handles the raw request to the API
-func (c *Client) request(m Method, endpoint string, query url.Values, payload []byte) (body []byte, res *http.Response, err error) {
+func (c *Client) request(ctx context.Context, m Method, endpoint string, query url.Values, payload []byte) (body []byte, res *http.Response, err error) {
 	uri := fmt.Sprintf("%s%s%s?%s", c.Host, c.Path, endpoint, query.Encode())
 	bod := bytes.NewReader(payload)
 
 	// start the request
 	var req *http.Request
-	if req, err = http.NewRequest(string(m), uri, bod); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, string(m), uri, bod); err != nil {
 		err = formatError("http request", err)
 		return
 	}
@@ -181,40 +487,76 @@ func (c *Client) request(m Method, endpoint string, query url.Values, payload []
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("User-Agent", "Go Coinbase AT 1.0")
+	if !c.DisableCompression {
+		// asked for here rather than left to the transport, so custom transports get it too
+		req.Header.Add("Accept-Encoding", "gzip")
+	}
 
-	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	resource := c.Path + endpoint
+	key := c.apiKey()
+
+	switch {
+	case c.public, c.sandbox, isPublicEndpoint(endpoint): // public and sandbox endpoints don't need to be signed
+	case endpoint == getServerTimeEndpoint:
+		// nor does the server time, which is used to correct the clock that signatures depend on
+	case key.usesJWT():
+		// CDP keys sign a token that covers the method, host, and path
+		var token string
+		if token, err = c.buildJWT(key, fmt.Sprintf("%s %s%s", m, req.URL.Host, resource)); err != nil {
+			err = formatError("generate jwt", err)
+			return
+		}
+		req.Header.Add("Authorization", "Bearer "+token)
+	default:
+		timestamp := strconv.FormatInt(c.now().Unix(), 10)
 
-	var signature string
-	if signature, err = c.sign(timestamp, m, resource, payload); err != nil {
-		err = formatError("generate signature", err)
-		return
+		var signature string
+		if signature, err = c.sign(key.Secret, timestamp, m, resource, payload); err != nil {
+			err = formatError("generate signature", err)
+			return
+		}
+
+		req.Header.Add("CB-ACCESS-KEY", key.Key)
+		req.Header.Add("CB-ACCESS-TIMESTAMP", timestamp)
+		req.Header.Add("CB-ACCESS-SIGN", signature)
 	}
 
-	req.Header.Add("CB-ACCESS-KEY", c.Key)
-	req.Header.Add("CB-ACCESS-TIMESTAMP", timestamp)
-	req.Header.Add("CB-ACCESS-SIGN", signature)
+	c.dumpRequest(req, payload)
 
-	// get the response and update last call time
-	c.lastCall = time.Now()
+	// get the response
 	if res, err = c.client.Do(req); err != nil {
-		err = formatError("http response", err)
+		err = networkError{formatError("http response", err)}
 		return
 	}
-	defer func() {
-		res.Body.Close()
-		c.lastCall = time.Now()
-	}()
+	defer res.Body.Close()
 
-	if body, err = ioutil.ReadAll(res.Body); err != nil {
-		err = formatError("read response body", err)
+	reader := io.Reader(res.Body)
+	if strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(res.Body); err != nil {
+			err = networkError{formatError("decompress response body", err)}
+			return
+		}
+		defer gz.Close()
+		reader = gz
+
+		// the response now looks like it does when the transport decompresses it
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Uncompressed = true
+	}
+
+	if body, err = ioutil.ReadAll(reader); err != nil {
+		err = networkError{formatError("read response body", err)}
 		return
 	}
+	c.dumpResponse(res, body)
 	return
 }
 
-func (c *Client) sign(timestamp string, method Method, resource string, data []byte) (sig string, err error) {
-	hash := hmac.New(sha256.New, []byte(c.Secret))
+func (c *Client) sign(secret string, timestamp string, method Method, resource string, data []byte) (sig string, err error) {
+	hash := hmac.New(sha256.New, []byte(secret))
 
 	message := fmt.Sprintf("%s%s%s%s", timestamp, method, resource, data)
 	if _, err = hash.Write([]byte(message)); err != nil {
@@ -224,11 +566,18 @@ func (c *Client) sign(timestamp string, method Method, resource string, data []b
 	return
 }
 
-func formatError(location string, err error) error {
-	return errors.New(location + ": " + err.Error())
+// EnableDebug logs everything down to debug level to stderr. It does nothing if Logger is
+// already set; use a handler with a debug level for that instead. To see requests and responses
+// in full, use EnableDebugDump.
+func (c *Client) EnableDebug() {
+	if c.Logger == nil {
+		c.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
 }
 
-// EnableDebug turns on some extra logging information
-func (c *Client) EnableDebug() {
-	c.debug = true
+// log writes a message to Logger, if one is set
+func (c *Client) log(level slog.Level, msg string, args ...any) {
+	if c.Logger != nil {
+		c.Logger.Log(context.Background(), level, msg, args...)
+	}
 }
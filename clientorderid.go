@@ -0,0 +1,54 @@
+package coinbasetrade
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// ClientOrderIDGenerator creates the client order id for orders that are placed without one. It
+// must be safe to use from multiple goroutines. Deterministic ids (e.g. derived from a strategy
+// name and a signal time) make it safe to retry placing an order, since the server won't accept
+// the same client order id twice.
+type ClientOrderIDGenerator interface {
+	NewClientOrderID() string
+}
+
+// ClientOrderIDFunc adapts a function to the ClientOrderIDGenerator interface.
+type ClientOrderIDFunc func() string
+
+// NewClientOrderID calls f.
+func (f ClientOrderIDFunc) NewClientOrderID() string {
+	return f()
+}
+
+// UUIDGenerator is the default ClientOrderIDGenerator, and creates random (version 4) UUIDs.
+type UUIDGenerator struct{}
+
+// NewClientOrderID returns a new random UUID.
+func (UUIDGenerator) NewClientOrderID() string {
+	return NewUUID()
+}
+
+// NewUUID returns a random (version 4) UUID, such as "1b4e28ba-2fa1-4d3b-a3f5-ef19b5a7633b".
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// the system's random source should never fail, but fall back to the time so the id is
+		// still very unlikely to be reused
+		binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()))
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// newClientOrderID creates a client order id using the client's generator
+func (c *Client) newClientOrderID() string {
+	if c.ClientOrderIDs == nil {
+		return NewUUID()
+	}
+	return c.ClientOrderIDs.NewClientOrderID()
+}
@@ -0,0 +1,80 @@
+package coinbasetrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrInvalidCredentials is returned by NewCheckedClient and CheckCredentials when the credentials
+// are missing or malformed.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// NewCheckedClient creates a client like NewClient, but returns an error wrapping
+// ErrInvalidCredentials if the credentials are missing or malformed, rather than letting the
+// first request fail. It doesn't check that the server accepts them; use Ping for that.
+func NewCheckedClient(config *ClientConfig) (*Client, error) {
+	c := NewClient(config)
+	if err := c.CheckCredentials(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CheckCredentials checks that the client has a complete set of credentials (and that a CDP
+// private key can be parsed), without making a request. Sandbox clients don't need any.
+func (c *Client) CheckCredentials() error {
+	if c.public || c.sandbox {
+		return nil
+	}
+
+	if err := c.checkKey(c.primaryKey()); err != nil {
+		return formatError("credentials", err)
+	}
+	for n, key := range c.Keys {
+		if err := c.checkKey(key); err != nil {
+			return formatError(fmt.Sprintf("credentials for key %d", n+1), err)
+		}
+	}
+	return nil
+}
+
+// checkKey checks one set of credentials
+func (c *Client) checkKey(key APIKey) error {
+	switch {
+	case key.usesJWT():
+		if key.KeyName == "" {
+			return fmt.Errorf("%w: API key name is missing", ErrInvalidCredentials)
+		}
+		if _, err := c.ecdsaKey(key.PrivateKey); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+		}
+	case key.Key == "" && key.Secret == "" && key.KeyName == "":
+		return fmt.Errorf("%w: no API key was given", ErrInvalidCredentials)
+	case key.KeyName != "":
+		return fmt.Errorf("%w: private key is missing", ErrInvalidCredentials)
+	case key.Key == "":
+		return fmt.Errorf("%w: API key is missing", ErrInvalidCredentials)
+	case key.Secret == "":
+		return fmt.Errorf("%w: API secret is missing", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// Ping makes a cheap authenticated request (listing a single account) to check that the server is
+// reachable and accepts the client's credentials. For a public client, it gets the server time
+// instead. If the key is rejected, the error unwraps to ErrUnauthorized.
+func (c *Client) Ping(ctx context.Context) error {
+	if c.public {
+		_, err := c.makeRequest(ctx, Get, getServerTimeEndpoint, url.Values{}, []byte{}, nil, nil)
+		return err
+	}
+	_, err := c.makeRequest(ctx, Get, listAccountsEndpoint, url.Values{"limit": {"1"}}, []byte{}, nil, nil)
+	return err
+}
+
+// Ping checks that the server is reachable.
+func (p *PublicClient) Ping(ctx context.Context) error {
+	return p.client.Ping(ctx)
+}
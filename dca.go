@@ -0,0 +1,232 @@
+package coinbasetrade
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Schedule decides when a recurring task runs next.
+type Schedule interface {
+	Next(after time.Time) time.Time // the first time strictly after `after`
+}
+
+// ScheduleFunc lets an ordinary function be used as a Schedule.
+type ScheduleFunc func(after time.Time) time.Time
+
+func (f ScheduleFunc) Next(after time.Time) time.Time {
+	return f(after)
+}
+
+// Every runs at whole multiples of d (e.g. every hour on the hour).
+func Every(d time.Duration) Schedule {
+	return ScheduleFunc(func(after time.Time) time.Time {
+		return after.Truncate(d).Add(d)
+	})
+}
+
+// DailyAt runs once a day at the given time in loc (UTC if nil).
+func DailyAt(hour, minute int, loc *time.Location) Schedule {
+	return WeeklyAt(-1, hour, minute, loc)
+}
+
+// WeeklyAt runs once a week on the given day, at the given time in loc (UTC if nil). A negative
+// day runs every day.
+func WeeklyAt(day time.Weekday, hour, minute int, loc *time.Location) Schedule {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return ScheduleFunc(func(after time.Time) time.Time {
+		a := after.In(loc)
+		next := time.Date(a.Year(), a.Month(), a.Day(), hour, minute, 0, 0, loc)
+		for !next.After(after) || (day >= 0 && next.Weekday() != day) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, hour, minute, 0, 0, loc)
+		}
+		return next
+	})
+}
+
+// DCAPlan is a recurring buy of a fixed amount of the quote currency.
+type DCAPlan struct {
+	ID          string // identifies the plan in the DCAStore; defaults to ProductID
+	ProductID   string
+	QuoteAmount decimal.Decimal
+	Schedule    Schedule
+
+	// If set, a limit "good till cancelled" order is placed this fraction below the current price
+	// (e.g. 0.01 for 1%) instead of a market order.
+	LimitDiscount decimal.Decimal
+}
+
+func (p DCAPlan) id() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.ProductID
+}
+
+// DCAStore remembers when each plan last ran, so a DCAScheduler can pick up where it left off
+// after a restart.
+type DCAStore interface {
+	LastRun(planID string) (time.Time, error) // zero if the plan has never run
+	SaveRun(planID string, scheduled time.Time, orderID string) error
+}
+
+// MemoryDCAStore is a DCAStore that doesn't persist anything, for when restarts don't matter.
+type MemoryDCAStore struct {
+	mu   sync.Mutex
+	runs map[string]time.Time
+}
+
+func (m *MemoryDCAStore) LastRun(planID string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.runs[planID], nil
+}
+
+func (m *MemoryDCAStore) SaveRun(planID string, scheduled time.Time, orderID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.runs == nil {
+		m.runs = make(map[string]time.Time)
+	}
+	m.runs[planID] = scheduled
+	return nil
+}
+
+// DCAScheduler places the orders for a set of DCAPlans on their schedules. When it starts, a plan
+// that missed runs while it was stopped is run once (not once per missed run), unless the most
+// recent missed run is more than MaxLate ago. Each order's client order id is made from the plan
+// and the time it was scheduled for, so Coinbase ignores it if it has already been placed, even if
+// the store wasn't updated. A failed run is reported to OnError and not retried.
+type DCAScheduler struct {
+	Store   DCAStore
+	MaxLate time.Duration // optional, how late a missed run can be and still be made
+	OnOrder func(DCAPlan, Order)
+	OnError func(DCAPlan, error)
+
+	client *Client
+	mu     sync.Mutex
+	plans  []DCAPlan
+}
+
+// NewDCAScheduler creates a scheduler that places orders with this client, and records its
+// progress in store (a MemoryDCAStore if nil).
+func (c *Client) NewDCAScheduler(store DCAStore) *DCAScheduler {
+	if store == nil {
+		store = &MemoryDCAStore{}
+	}
+	return &DCAScheduler{
+		Store:  store,
+		client: c,
+	}
+}
+
+// Add includes a plan in the schedule.
+func (s *DCAScheduler) Add(plan DCAPlan) {
+	s.mu.Lock()
+	s.plans = append(s.plans, plan)
+	s.mu.Unlock()
+}
+
+// Run places orders as they fall due, until the context is done. A plan that has never run makes
+// its first order at its next scheduled time, not straight away.
+func (s *DCAScheduler) Run(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		plans := append([]DCAPlan(nil), s.plans...)
+		s.mu.Unlock()
+
+		now := time.Now()
+		wake := now.Add(time.Minute) // check for new plans at least this often
+		for _, plan := range plans {
+			last, err := s.Store.LastRun(plan.id())
+			if err != nil {
+				s.fail(plan, err)
+				continue
+			}
+			if last.IsZero() {
+				// never run, so start from now rather than the beginning of time
+				last = now
+				if err = s.Store.SaveRun(plan.id(), now, ""); err != nil {
+					s.fail(plan, err)
+					continue
+				}
+			}
+
+			next := plan.Schedule.Next(last)
+			if next.After(now) {
+				if next.Before(wake) {
+					wake = next
+				}
+				continue
+			}
+
+			// only the most recent of any missed runs is made
+			for n := plan.Schedule.Next(next); !n.After(now); n = plan.Schedule.Next(n) {
+				next = n
+			}
+			s.run(plan, next, now)
+			if n := plan.Schedule.Next(next); n.Before(wake) {
+				wake = n
+			}
+		}
+
+		if err := sleep(ctx, time.Until(wake)); err != nil {
+			return err
+		}
+	}
+}
+
+// run places one order for a plan, and records it
+func (s *DCAScheduler) run(plan DCAPlan, scheduled, now time.Time) {
+	var (
+		order Order
+		err   error
+	)
+	if s.MaxLate <= 0 || now.Sub(scheduled) <= s.MaxLate {
+		clientOrderID := fmt.Sprintf("dca-%s-%d", plan.id(), scheduled.Unix())
+		if order, err = s.place(plan, clientOrderID); err != nil {
+			s.fail(plan, err)
+		} else if s.OnOrder != nil {
+			s.OnOrder(plan, order)
+		}
+	}
+
+	if err = s.Store.SaveRun(plan.id(), scheduled, order.ID); err != nil {
+		s.fail(plan, err)
+	}
+}
+
+// place places the order for a plan
+func (s *DCAScheduler) place(plan DCAPlan, clientOrderID string) (order Order, err error) {
+	if !plan.LimitDiscount.IsPositive() {
+		order, _, err = s.client.PlaceMarketIOC(clientOrderID, plan.ProductID, Buy, plan.QuoteAmount)
+		return
+	}
+
+	var product Product
+	if product, err = s.client.GetProduct(plan.ProductID); err != nil {
+		return
+	}
+
+	price := product.Price.Mul(decimal.NewFromInt(1).Sub(plan.LimitDiscount))
+	price = RoundToIncrement(price, product.priceIncrement(), RoundDown)
+	if !price.IsPositive() {
+		err = fmt.Errorf("no price for %s", plan.ProductID)
+		return
+	}
+
+	size := RoundToIncrement(plan.QuoteAmount.Div(price), product.BaseIncrement, RoundDown)
+	order, _, err = s.client.PlaceLimitGTC(clientOrderID, plan.ProductID, Buy, size, price, false)
+	return
+}
+
+func (s *DCAScheduler) fail(plan DCAPlan, err error) {
+	if s.OnError != nil {
+		s.OnError(plan, err)
+	}
+}
@@ -0,0 +1,276 @@
+package coinbasetrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// DownloadProgress reports how far a Downloader has got with one product.
+type DownloadProgress struct {
+	ProductID string
+	Kind      string  // "candles" or "trades"
+	Count     int     // how many have been downloaded so far, in this run
+	Fraction  float64 // how much of the time range has been covered, from 0 to 1
+	Done      bool
+}
+
+// Downloader fetches all of the candles and/or market trades of several products over a period,
+// handing them to OnCandles and OnTrades as they arrive. Candles are downloaded several chunks at
+// a time with BackfillCandles (so a client's CandleStore is used), and trades a page at a time
+// going back from End.
+//
+// If StateFile is set, the download can be resumed: how far each product has got is saved there
+// after every batch has been handed over, and a later Run with the same settings carries on from
+// where it stopped. A batch whose handler returned an error is sent again.
+type Downloader struct {
+	ProductIDs  []string
+	Start       time.Time
+	End         time.Time   // defaults to now, or to the End of the download being resumed
+	Granularity Granularity // the candles to download; leave empty for none
+	Trades      bool        // whether to download market trades
+	Workers     int         // concurrent candle requests (default 4)
+	StateFile   string      // where to save progress, so the download can be resumed
+
+	OnCandles  func(productID string, candles []Candle) error // oldest first
+	OnTrades   func(productID string, trades []Trade) error   // each page newest first, going back in time
+	OnProgress func(DownloadProgress)
+
+	client *Client
+}
+
+// downloadState is what a Downloader saves to resume from
+type downloadState struct {
+	Start       time.Time                        `json:"start"`
+	End         time.Time                        `json:"end"`
+	Granularity Granularity                      `json:"granularity"`
+	Products    map[string]*productDownloadState `json:"products"`
+}
+
+type productDownloadState struct {
+	CandlesFrom    time.Time `json:"candles_from,omitempty"` // the start of the next candle to download
+	CandlesDone    bool      `json:"candles_done,omitempty"`
+	TradesEnd      time.Time `json:"trades_end,omitempty"`      // where the next page of trades ends
+	TradesBoundary []string  `json:"trades_boundary,omitempty"` // ids of trades already handed over at TradesEnd
+	TradesDone     bool      `json:"trades_done,omitempty"`
+}
+
+// NewDownloader creates a Downloader for the candles of some products over a period. Set Trades to
+// download market trades too, and OnCandles and OnTrades to receive them.
+func (c *Client) NewDownloader(start, end time.Time, granularity Granularity, productIDs ...string) *Downloader {
+	return &Downloader{
+		ProductIDs:  productIDs,
+		Start:       start,
+		End:         end,
+		Granularity: granularity,
+		Workers:     defaultBackfillWorkers,
+		client:      c,
+	}
+}
+
+// Run downloads everything, one product at a time, until it is finished, a request or handler
+// fails, or the context is done.
+func (d *Downloader) Run(ctx context.Context) error {
+	state, err := d.loadState()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range d.ProductIDs {
+		p := state.Products[id]
+		if p == nil {
+			p = &productDownloadState{}
+			state.Products[id] = p
+		}
+
+		if d.Granularity != "" && !p.CandlesDone {
+			if err = d.candles(ctx, id, p, state); err != nil {
+				return err
+			}
+		}
+		if d.Trades && !p.TradesDone {
+			if err = d.trades(ctx, id, p, state); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// candles downloads the candles of one product
+func (d *Downloader) candles(ctx context.Context, id string, p *productDownloadState, state *downloadState) error {
+	from := d.Start
+	if !p.CandlesFrom.IsZero() {
+		from = p.CandlesFrom
+	}
+	if from.After(d.End) {
+		p.CandlesDone = true
+		return d.saveState(state)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	candles, errc := d.client.BackfillCandles(ctx, id, from, d.End, d.Granularity, d.Workers)
+
+	count := 0
+	var batch []Candle
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if d.OnCandles != nil {
+			if err := d.OnCandles(id, batch); err != nil {
+				return err
+			}
+		}
+		count += len(batch)
+		last := batch[len(batch)-1]
+		p.CandlesFrom = time.Unix(last.StartUnix, 0).Add(d.Granularity.Duration())
+		batch = nil
+		d.progress(id, "candles", count, p.CandlesFrom, false)
+		return d.saveState(state)
+	}
+
+	for c := range candles {
+		if batch = append(batch, c); len(batch) == maxCandlesPerRequest {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := <-errc; err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	p.CandlesDone = true
+	d.progress(id, "candles", count, d.End, true)
+	return d.saveState(state)
+}
+
+// trades downloads the market trades of one product
+func (d *Downloader) trades(ctx context.Context, id string, p *productDownloadState, state *downloadState) error {
+	pager := d.client.PageMarketTrades(id, MarketTradesParameters{Start: d.Start, End: d.End})
+	if !p.TradesEnd.IsZero() {
+		pager.params.End = p.TradesEnd
+		for _, tid := range p.TradesBoundary {
+			pager.seen[tid] = true
+		}
+	}
+
+	count := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		trades, err := pager.Next()
+		if err != nil {
+			return err
+		}
+		if len(trades) == 0 {
+			break
+		}
+		if d.OnTrades != nil {
+			if err = d.OnTrades(id, trades); err != nil {
+				return err
+			}
+		}
+		count += len(trades)
+
+		p.TradesEnd, p.TradesBoundary = pager.params.End, nil
+		for tid := range pager.seen {
+			p.TradesBoundary = append(p.TradesBoundary, tid)
+		}
+		d.progress(id, "trades", count, p.TradesEnd, false)
+		if err = d.saveState(state); err != nil {
+			return err
+		}
+	}
+
+	p.TradesDone = true
+	d.progress(id, "trades", count, d.Start, true)
+	return d.saveState(state)
+}
+
+// progress reports how far a product has got; reached is the time the download has got to
+func (d *Downloader) progress(id, kind string, count int, reached time.Time, done bool) {
+	if d.OnProgress == nil {
+		return
+	}
+
+	fraction := 1.0
+	if total := d.End.Sub(d.Start); total > 0 && !done {
+		covered := reached.Sub(d.Start) // candles go forward in time
+		if kind == "trades" {
+			covered = d.End.Sub(reached) // and trades go back
+		}
+		fraction = min(max(float64(covered)/float64(total), 0), 1)
+	}
+	d.OnProgress(DownloadProgress{ProductID: id, Kind: kind, Count: count, Fraction: fraction, Done: done})
+}
+
+// loadState reads the state file, or starts afresh if there isn't one. If End isn't set, it is
+// taken from the state file, or set to now.
+func (d *Downloader) loadState() (*downloadState, error) {
+	fresh := func() *downloadState {
+		if d.End.IsZero() {
+			d.End = d.client.now()
+		}
+		return &downloadState{
+			Start:       d.Start,
+			End:         d.End,
+			Granularity: d.Granularity,
+			Products:    make(map[string]*productDownloadState),
+		}
+	}
+	if d.StateFile == "" {
+		return fresh(), nil
+	}
+
+	data, err := os.ReadFile(d.StateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fresh(), nil
+	}
+	if err != nil {
+		return nil, formatError("read download state", err)
+	}
+
+	var saved downloadState
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return nil, formatError("read download state", err)
+	}
+	if d.End.IsZero() {
+		d.End = saved.End
+	}
+	if !saved.Start.Equal(d.Start) || !saved.End.Equal(d.End) || saved.Granularity != d.Granularity {
+		return nil, formatError("read download state", fmt.Errorf("%s is for a different download (%s to %s, %s)",
+			d.StateFile, saved.Start.Format(time.RFC3339), saved.End.Format(time.RFC3339), saved.Granularity))
+	}
+	if saved.Products == nil {
+		saved.Products = make(map[string]*productDownloadState)
+	}
+	return &saved, nil
+}
+
+// saveState writes the state file, if there is one
+func (d *Downloader) saveState(state *downloadState) error {
+	if d.StateFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = writeFileAtomic(d.StateFile, data)
+	}
+	if err != nil {
+		return formatError("save download state", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package coinbasetrade
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+)
+
+// redactedHeaders hold credentials or signatures, so their values are never dumped
+var redactedHeaders = []string{"CB-ACCESS-KEY", "CB-ACCESS-SIGN", "Authorization", "Cookie", "Set-Cookie"}
+
+// redact returns a copy of the headers with the secret values replaced
+func redact(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range redactedHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; ok {
+			h.Set(k, "REDACTED")
+		}
+	}
+	return h
+}
+
+// EnableDebugDump turns on debug logging (see EnableDebug), and also writes every request and
+// response in full to w, with the API key, signature, and token redacted, so it can be attached
+// to a bug report.
+func (c *Client) EnableDebugDump(w io.Writer) {
+	c.EnableDebug()
+	c.Dump = w
+}
+
+// dumpRequest writes a request to Dump, if set
+func (c *Client) dumpRequest(req *http.Request, payload []byte) {
+	if c.Dump == nil {
+		return
+	}
+
+	r := req.Clone(req.Context())
+	r.Header = redact(req.Header)
+	r.Body = io.NopCloser(bytes.NewReader(payload))
+	r.ContentLength = int64(len(payload))
+
+	data, err := httputil.DumpRequestOut(r, true)
+	if err != nil {
+		return
+	}
+	c.Dump.Write(append(data, '\n'))
+}
+
+// dumpResponse writes a response and the body that was read from it to Dump, if set
+func (c *Client) dumpResponse(res *http.Response, body []byte) {
+	if c.Dump == nil {
+		return
+	}
+
+	r := *res
+	r.Header = redact(res.Header)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	data, err := httputil.DumpResponse(&r, true)
+	if err != nil {
+		return
+	}
+	c.Dump.Write(append(data, '\n'))
+}
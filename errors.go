@@ -0,0 +1,95 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors that an APIError unwraps to, based on the HTTP status of the response. Use these with
+// errors.Is to handle a category of failure without inspecting the status code.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned when the server responds with anything other than a success code. Use
+// errors.As to get at it from the error returned by any method.
+type APIError struct {
+	StatusCode int    // the HTTP status code
+	Code       string // the error code from the response, i.e. "PERMISSION_DENIED"
+	Message    string
+	Details    string
+	Body       []byte // the raw response
+	RequestID  string // Coinbase's id for the request, if the response included one
+
+	hint string // extra information to help debug, included in Error()
+}
+
+// newAPIError builds an APIError from a failed response
+func newAPIError(res *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: res.StatusCode,
+		Body:       body,
+		RequestID:  requestID(res.Header),
+	}
+
+	raw := struct {
+		Error        string          `json:"error"`
+		Message      string          `json:"message"`
+		ErrorDetails string          `json:"error_details"`
+		Details      json.RawMessage `json:"details"`
+	}{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return e
+	}
+
+	e.Code, e.Message, e.Details = raw.Error, raw.Message, raw.ErrorDetails
+	if e.Details == "" && len(raw.Details) > 0 && string(raw.Details) != "[]" && string(raw.Details) != "null" {
+		e.Details = string(raw.Details)
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		// if the response couldn't be understood, use the body as the message
+		msg = fmt.Sprintf("(%d) %s", e.StatusCode, e.Body)
+	}
+	if e.hint != "" {
+		msg += " [" + e.hint + "]"
+	}
+	return msg
+}
+
+// Unwrap returns the general category of error (ErrNotFound, ErrRateLimited, etc) based on the
+// status code, or nil if it doesn't fit any of them.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// formatError adds the location an error occurred to its message, keeping the original error
+// available to errors.Is and errors.As
+func formatError(location string, err error) error {
+	return fmt.Errorf("%s: %w", location, err)
+}
@@ -0,0 +1,161 @@
+package coinbasetrade
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+var fillsCSVHeader = []string{"trade_time", "product_id", "side", "size", "size_in_quote", "price", "commission", "liquidity", "order_id", "trade_id"}
+
+// writeFillRows writes fills to a csv writer, one row each
+func writeFillRows(cw *csv.Writer, fills []Fill) error {
+	for _, f := range fills {
+		sizeInQuote := "false"
+		if f.SizeInQuote {
+			sizeInQuote = "true"
+		}
+		row := []string{
+			f.TradeTime.UTC().Format(time.RFC3339Nano),
+			f.ProductID,
+			string(f.Side),
+			f.Size.String(),
+			sizeInQuote,
+			f.Price.String(),
+			f.Commission.String(),
+			string(f.LiquidityIndicator),
+			f.OrderID,
+			f.TradeID,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes the fills in the current page to w as CSV, with a header row. Times are in UTC.
+func (l FillList) WriteCSV(w io.Writer) (err error) {
+	cw := csv.NewWriter(w)
+	if err = cw.Write(fillsCSVHeader); err != nil {
+		return formatError("write fills csv", err)
+	}
+	if err = writeFillRows(cw, l.Fills); err != nil {
+		return formatError("write fills csv", err)
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		err = formatError("write fills csv", err)
+	}
+	return
+}
+
+// ExportFills writes every fill matching the parameters to w as CSV, fetching as many pages as
+// needed. It returns the number of fills written.
+func (c *Client) ExportFills(w io.Writer, params ListFillsParameters) (n int, err error) {
+	var l FillList
+	if l, err = c.ListFills(params); err != nil {
+		return
+	}
+
+	cw := csv.NewWriter(w)
+	defer func() {
+		cw.Flush()
+		if err == nil {
+			if err = cw.Error(); err != nil {
+				err = formatError("export fills", err)
+			}
+		}
+	}()
+
+	if err = cw.Write(fillsCSVHeader); err != nil {
+		err = formatError("export fills", err)
+		return
+	}
+
+	for l.Next() {
+		if err = writeFillRows(cw, l.Fills); err != nil {
+			err = formatError("export fills", err)
+			return
+		}
+		n += len(l.Fills)
+
+		if err = l.NextPage(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+var candlesCSVHeader = []string{"start", "product_id", "open", "high", "low", "close", "volume"}
+
+// WriteCandlesCSV writes a product's candles to w as CSV, with a header row, in the order given.
+// Times are in UTC. The columns are named like those of the other CSV writers, so the files can
+// be loaded into pandas, DuckDB, etc and joined on product_id.
+func WriteCandlesCSV(w io.Writer, productID string, candles []Candle) (err error) {
+	cw := csv.NewWriter(w)
+	if err = cw.Write(candlesCSVHeader); err != nil {
+		return formatError("write candles csv", err)
+	}
+
+	for _, c := range candles {
+		row := []string{
+			time.Unix(c.StartUnix, 0).UTC().Format(time.RFC3339Nano),
+			productID,
+			c.Open.String(),
+			c.High.String(),
+			c.Low.String(),
+			c.Close.String(),
+			c.Volume.String(),
+		}
+		if err = cw.Write(row); err != nil {
+			return formatError("write candles csv", err)
+		}
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		err = formatError("write candles csv", err)
+	}
+	return
+}
+
+var tradesCSVHeader = []string{"time", "product_id", "side", "size", "price", "trade_id", "bid", "ask"}
+
+// WriteTradesCSV writes market trades to w as CSV, with a header row, in the order given. Times
+// are in UTC, and the bid and ask are left empty when the api didn't send them.
+func WriteTradesCSV(w io.Writer, trades []Trade) (err error) {
+	cw := csv.NewWriter(w)
+	if err = cw.Write(tradesCSVHeader); err != nil {
+		return formatError("write trades csv", err)
+	}
+
+	for _, t := range trades {
+		bid, ask := "", ""
+		if !t.Bid.IsZero() {
+			bid = t.Bid.String()
+		}
+		if !t.Ask.IsZero() {
+			ask = t.Ask.String()
+		}
+		row := []string{
+			t.Time.UTC().Format(time.RFC3339Nano),
+			t.ProductID,
+			string(t.Side),
+			t.Size.String(),
+			t.Price.String(),
+			t.ID,
+			bid,
+			ask,
+		}
+		if err = cw.Write(row); err != nil {
+			return formatError("write trades csv", err)
+		}
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		err = formatError("write trades csv", err)
+	}
+	return
+}
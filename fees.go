@@ -0,0 +1,193 @@
+package coinbasetrade
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type GSTType string
+
+const (
+	GSTInclusive GSTType = "INCLUSIVE"
+	GSTExclusive GSTType = "EXCLUSIVE"
+)
+
+// FeeTier is the pricing tier the user is currently in, based on their trading volume over the
+// last 30 days (USDFrom up to USDTo) and assets on the platform (AOPFrom up to AOPTo). USDTo is
+// zero for the highest tier.
+type FeeTier struct {
+	PricingTier  string          `json:"pricing_tier"`
+	USDFrom      decimal.Decimal `json:"usd_from"`
+	USDTo        decimal.Decimal `json:"usd_to"`
+	TakerFeeRate decimal.Decimal `json:"taker_fee_rate"`
+	MakerFeeRate decimal.Decimal `json:"maker_fee_rate"`
+	AOPFrom      decimal.Decimal `json:"aop_from"`
+	AOPTo        decimal.Decimal `json:"aop_to"`
+}
+
+// UnmarshalJSON allows the amounts and rates to be empty, as USDTo, AOPFrom and AOPTo often are.
+func (t *FeeTier) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		PricingTier  string `json:"pricing_tier"`
+		USDFrom      string `json:"usd_from"`
+		USDTo        string `json:"usd_to"`
+		TakerFeeRate string `json:"taker_fee_rate"`
+		MakerFeeRate string `json:"maker_fee_rate"`
+		AOPFrom      string `json:"aop_from"`
+		AOPTo        string `json:"aop_to"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	t.PricingTier = aux.PricingTier
+	t.USDFrom = parseOptionalDecimal(aux.USDFrom)
+	t.USDTo = parseOptionalDecimal(aux.USDTo)
+	t.TakerFeeRate = parseOptionalDecimal(aux.TakerFeeRate)
+	t.MakerFeeRate = parseOptionalDecimal(aux.MakerFeeRate)
+	t.AOPFrom = parseOptionalDecimal(aux.AOPFrom)
+	t.AOPTo = parseOptionalDecimal(aux.AOPTo)
+	return nil
+}
+
+type TransactionSummary struct {
+	TotalVolume             decimal.Decimal `json:"total_volume"`
+	TotalFees               decimal.Decimal `json:"total_fees"`
+	FeeTier                 FeeTier         `json:"fee_tier"`
+	AdvancedTradeOnlyVolume decimal.Decimal `json:"advanced_trade_only_volume"`
+	AdvancedTradeOnlyFees   decimal.Decimal `json:"advanced_trade_only_fees"`
+	CoinbaseProVolume       decimal.Decimal `json:"coinbase_pro_volume"`
+	CoinbaseProFees         decimal.Decimal `json:"coinbase_pro_fees"`
+	MarginRate              struct {
+		Value decimal.Decimal `json:"value"`
+	} `json:"margin_rate"`
+	GoodsAndServicesTax struct {
+		Rate decimal.Decimal `json:"rate"`
+		Type GSTType         `json:"type"`
+	} `json:"goods_and_services_tax"`
+}
+
+type TransactionSummaryParameters struct {
+	StartDate          time.Time   `cbt:"start_date"`
+	EndDate            time.Time   `cbt:"end_date"`
+	UserNativeCurrency string      `cbt:"user_native_currency"`
+	ProductType        ProductType `cbt:"product_type"`
+}
+
+// GetTransactionSummary returns the user's total volume and fees, and their current fee tier.
+func (c *Client) GetTransactionSummary(params TransactionSummaryParameters) (summary TransactionSummary, err error) {
+	query, err := parametersToValues(params)
+	if err != nil {
+		return
+	}
+
+	_, err = c.makeRequest(context.Background(), Get, getTransactionSummaryEndpoint, query, []byte{}, &summary, nil)
+	return
+}
+
+// FeeTierProgress shows how close the user is to the next fee tier.
+type FeeTierProgress struct {
+	Volume     decimal.Decimal // trading volume over the last 30 days, in USD
+	Tier       FeeTier         // the current tier
+	NextTierAt decimal.Decimal // the volume the next tier starts at; zero if already in the highest tier
+	Remaining  decimal.Decimal // additional volume needed to reach the next tier
+}
+
+// FeeTierProgress works out how much more volume is needed to reach the next fee tier.
+func (s TransactionSummary) FeeTierProgress() (p FeeTierProgress) {
+	p = FeeTierProgress{
+		Volume:     s.TotalVolume,
+		Tier:       s.FeeTier,
+		NextTierAt: s.FeeTier.USDTo,
+	}
+	if p.NextTierAt.IsPositive() {
+		p.Remaining = decimal.Max(p.NextTierAt.Sub(p.Volume), decimal.Zero)
+	}
+	return
+}
+
+// GetFeeTierProgress fetches the transaction summary and reports how much more volume is needed to
+// reach the next fee tier.
+func (c *Client) GetFeeTierProgress() (p FeeTierProgress, err error) {
+	var summary TransactionSummary
+	if summary, err = c.GetTransactionSummary(TransactionSummaryParameters{}); err != nil {
+		return
+	}
+	return summary.FeeTierProgress(), nil
+}
+
+// FeeEstimator calculates the fees for an order before it is placed, using the user's current fee
+// tier. The tier is fetched with GetTransactionSummary the first time it is needed, and again
+// whenever it is older than MaxAge. It is safe to use from multiple goroutines.
+type FeeEstimator struct {
+	MaxAge time.Duration // how long to use the fee tier before fetching it again (default 1 hour)
+
+	client  *Client
+	mu      sync.Mutex
+	tier    FeeTier
+	fetched time.Time
+}
+
+// NewFeeEstimator creates a FeeEstimator that uses this client to look up the fee tier.
+func (c *Client) NewFeeEstimator() *FeeEstimator {
+	return &FeeEstimator{
+		MaxAge: time.Hour,
+		client: c,
+	}
+}
+
+// Refresh fetches the current fee tier, regardless of its age.
+func (f *FeeEstimator) Refresh() (err error) {
+	var summary TransactionSummary
+	if summary, err = f.client.GetTransactionSummary(TransactionSummaryParameters{}); err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	f.tier, f.fetched = summary.FeeTier, time.Now()
+	f.mu.Unlock()
+	return
+}
+
+// Tier returns the current fee tier, fetching it if needed.
+func (f *FeeEstimator) Tier() (tier FeeTier, err error) {
+	f.mu.Lock()
+	stale := f.fetched.IsZero() || time.Since(f.fetched) > f.MaxAge
+	f.mu.Unlock()
+
+	if stale {
+		if err = f.Refresh(); err != nil {
+			return
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tier, nil
+}
+
+// Estimate returns the fee for trading `size` of the base currency at `price`, in the quote
+// currency. Use LiquidityMaker for orders expected to rest on the book (i.e. post only limit
+// orders), and LiquidityTaker for orders expected to fill immediately. If the liquidity is unknown
+// the higher of the two rates is used, so the estimate is never too low.
+func (f *FeeEstimator) Estimate(size, price decimal.Decimal, liquidity LiquidityIndicator) (fee decimal.Decimal, err error) {
+	var tier FeeTier
+	if tier, err = f.Tier(); err != nil {
+		return
+	}
+
+	rate := decimal.Max(tier.MakerFeeRate, tier.TakerFeeRate)
+	switch liquidity {
+	case LiquidityMaker:
+		rate = tier.MakerFeeRate
+	case LiquidityTaker:
+		rate = tier.TakerFeeRate
+	}
+
+	fee = size.Mul(price).Mul(rate)
+	return
+}
@@ -0,0 +1,97 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FCMTradingSessionDetails is the trading session of a futures product, which (unlike spot
+// trading) isn't open all the time.
+type FCMTradingSessionDetails struct {
+	IsSessionOpen bool      `json:"is_session_open"`
+	OpenTime      time.Time `json:"open_time"`
+	CloseTime     time.Time `json:"close_time"`
+}
+
+// UnmarshalJSON allows the session times to be empty.
+func (d *FCMTradingSessionDetails) UnmarshalJSON(data []byte) error {
+	type details FCMTradingSessionDetails // avoids calling this method again
+	aux := struct {
+		*details
+		OpenTime  string `json:"open_time"`
+		CloseTime string `json:"close_time"`
+	}{details: (*details)(d)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.OpenTime = parseOptionalTime(aux.OpenTime)
+	d.CloseTime = parseOptionalTime(aux.CloseTime)
+	return nil
+}
+
+// PerpetualDetails are the extra details of a perpetual futures product. They are zero for
+// contracts that expire.
+type PerpetualDetails struct {
+	OpenInterest decimal.Decimal `json:"open_interest"`
+	FundingRate  decimal.Decimal `json:"funding_rate"`
+	FundingTime  time.Time       `json:"funding_time"`
+	MaxLeverage  decimal.Decimal `json:"max_leverage"`
+}
+
+// UnmarshalJSON allows every field to be empty.
+func (d *PerpetualDetails) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		OpenInterest string `json:"open_interest"`
+		FundingRate  string `json:"funding_rate"`
+		FundingTime  string `json:"funding_time"`
+		MaxLeverage  string `json:"max_leverage"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	d.OpenInterest = parseOptionalDecimal(aux.OpenInterest)
+	d.FundingRate = parseOptionalDecimal(aux.FundingRate)
+	d.FundingTime = parseOptionalTime(aux.FundingTime)
+	d.MaxLeverage = parseOptionalDecimal(aux.MaxLeverage)
+	return nil
+}
+
+// FutureProductDetails are the details of a futures contract.
+type FutureProductDetails struct {
+	Venue                  string             `json:"venue"`
+	ContractCode           string             `json:"contract_code"`
+	ContractExpiry         time.Time          `json:"contract_expiry"`
+	ContractSize           decimal.Decimal    `json:"contract_size"`
+	ContractRootUnit       string             `json:"contract_root_unit"`
+	GroupDescription       string             `json:"group_description"`
+	GroupShortDescription  string             `json:"group_short_description"`
+	ContractExpiryTimezone string             `json:"contract_expiry_timezone"`
+	RiskManagedBy          string             `json:"risk_managed_by"` // e.g. "MANAGED_BY_FCM" or "MANAGED_BY_VENUE"
+	ContractExpiryType     ContractExpiryType `json:"contract_expiry_type"`
+	ContractDisplayName    string             `json:"contract_display_name"`
+	ContractExpiryName     string             `json:"contract_expiry_name"`
+	NonCrypto              bool               `json:"non_crypto"`
+	PerpetualDetails       PerpetualDetails   `json:"perpetual_details"`
+}
+
+// UnmarshalJSON allows the contract expiry (which perpetual contracts don't have) and size to be
+// empty.
+func (d *FutureProductDetails) UnmarshalJSON(data []byte) error {
+	type details FutureProductDetails // avoids calling this method again
+	aux := struct {
+		*details
+		ContractExpiry string `json:"contract_expiry"`
+		ContractSize   string `json:"contract_size"`
+	}{details: (*details)(d)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.ContractExpiry = parseOptionalTime(aux.ContractExpiry)
+	d.ContractSize = parseOptionalDecimal(aux.ContractSize)
+	return nil
+}
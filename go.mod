@@ -1,5 +1,8 @@
 module github.com/jmacwhyte/go-coinbase-trade
 
-go 1.17
+go 1.23
 
-require github.com/shopspring/decimal v1.3.1
+require (
+	github.com/gorilla/websocket v1.5.0
+	github.com/shopspring/decimal v1.3.1
+)
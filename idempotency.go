@@ -0,0 +1,126 @@
+package coinbasetrade
+
+import (
+	"sync"
+	"time"
+)
+
+// SubmissionState is how far an order submission got.
+type SubmissionState string
+
+const (
+	// the order is about to be sent, or was sent but no reply was received, so it may or may not
+	// have been placed
+	SubmissionPending  SubmissionState = "PENDING"
+	SubmissionPlaced   SubmissionState = "PLACED"
+	SubmissionRejected SubmissionState = "REJECTED"
+)
+
+// OrderSubmission is the record of an attempt to place an order, keyed by its client order id.
+type OrderSubmission struct {
+	ClientOrderID      string
+	ProductID          string
+	Side               Side
+	OrderConfiguration OrderConfiguration
+	Options            OrderOptions
+	State              SubmissionState
+	OrderID            string           // set once the order is placed
+	Reason             CreateOrderError // set if the order was rejected
+	UpdatedAt          time.Time
+}
+
+// Order returns the order that was placed, as CreateOrder would have returned it.
+func (s OrderSubmission) Order() Order {
+	return Order{
+		ID:                 s.OrderID,
+		Product:            s.ProductID,
+		ClientOrderID:      s.ClientOrderID,
+		Side:               s.Side,
+		OrderConfiguration: s.OrderConfiguration,
+	}
+}
+
+// IdempotencyStore records order submissions, so a program that stops while placing an order can
+// tell afterwards whether it was sent. When a Client has a store, CreateOrder saves a pending
+// record before each order is sent, and updates it with the result. An order whose client order
+// id is already recorded as placed isn't sent again; CreateOrder returns the recorded order
+// instead. Pending submissions can be sent again safely with the same client order id, since the
+// server won't place two orders with the same id.
+type IdempotencyStore interface {
+	GetSubmission(clientOrderID string) (sub OrderSubmission, found bool, err error)
+	SaveSubmission(sub OrderSubmission) error
+	PendingSubmissions() ([]OrderSubmission, error)
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that doesn't persist anything. It prevents
+// duplicate orders within one run of a program, but not across restarts.
+type MemoryIdempotencyStore struct {
+	mu          sync.Mutex
+	submissions map[string]OrderSubmission
+}
+
+func (m *MemoryIdempotencyStore) GetSubmission(clientOrderID string) (sub OrderSubmission, found bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, found = m.submissions[clientOrderID]
+	return
+}
+
+func (m *MemoryIdempotencyStore) SaveSubmission(sub OrderSubmission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.submissions == nil {
+		m.submissions = make(map[string]OrderSubmission)
+	}
+	m.submissions[sub.ClientOrderID] = sub
+	return nil
+}
+
+func (m *MemoryIdempotencyStore) PendingSubmissions() (pending []OrderSubmission, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sub := range m.submissions {
+		if sub.State == SubmissionPending {
+			pending = append(pending, sub)
+		}
+	}
+	return
+}
+
+// beginSubmission checks the store for an order that has already been placed with the client
+// order id, and otherwise records that it is about to be sent. If done is true, the order has
+// already been placed and is returned.
+func (c *Client) beginSubmission(sub OrderSubmission) (order Order, done bool, err error) {
+	var prev OrderSubmission
+	var found bool
+	if prev, found, err = c.Submissions.GetSubmission(sub.ClientOrderID); err != nil {
+		err = formatError("get order submission", err)
+		return
+	}
+	if found && prev.State == SubmissionPlaced {
+		return prev.Order(), true, nil
+	}
+
+	sub.State, sub.UpdatedAt = SubmissionPending, time.Now()
+	if err = c.Submissions.SaveSubmission(sub); err != nil {
+		err = formatError("save order submission", err)
+	}
+	return
+}
+
+// finishSubmission records the result of sending an order. If no reply was received, the record
+// is left pending, since the order may have been placed. A failure to save is ignored for the
+// same reason: a pending record is safe to send again.
+func (c *Client) finishSubmission(sub OrderSubmission, order Order, reason CreateOrderError, err error) {
+	switch {
+	case reason != "":
+		sub.State, sub.Reason = SubmissionRejected, reason
+	case err == nil:
+		sub.State, sub.OrderID = SubmissionPlaced, order.ID
+		sub.OrderConfiguration = order.OrderConfiguration
+	default:
+		return
+	}
+	sub.UpdatedAt = time.Now()
+	_ = c.Submissions.SaveSubmission(sub)
+}
@@ -0,0 +1,113 @@
+package coinbasetrade
+
+import (
+	"context"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The interfaces below cover the API methods of *Client, so application code can depend on them
+// and swap in a mock or fake for testing. Use the smallest one that covers what the code needs.
+// Helpers that are built on a *Client (NewTWAP, NewOCOManager, NewWebsocket, etc) aren't included.
+
+// AccountService lists and gets accounts.
+type AccountService interface {
+	ListAccounts(params ListAccountsParameters) (AccountList, error)
+	IterAccounts(params ListAccountsParameters) *Iterator[Account]
+	StreamAccounts(ctx context.Context, params ListAccountsParameters) (<-chan Account, <-chan error)
+	Accounts(ctx context.Context, params ListAccountsParameters) iter.Seq2[Account, error]
+	GetAccount(id string) (Account, error)
+	GetAccountByCurrency(currency string) (Account, error)
+}
+
+// OrderPlacer places, cancels, and checks on orders. *PaperClient also satisfies it.
+type OrderPlacer interface {
+	CreateOrder(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration) (Order, CreateOrderError, error)
+	CreateOrderWithOptions(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration, opts OrderOptions) (Order, CreateOrderError, error)
+	PlaceOrder(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration, opts OrderOptions) (Order, error)
+	PlaceMarketIOC(clientOrderId string, productId string, side Side, size decimal.Decimal) (Order, CreateOrderError, error)
+	PlaceMarketIOCBase(clientOrderId string, productId string, side Side, baseSize decimal.Decimal) (Order, CreateOrderError, error)
+	PlaceLimitGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, postOnly bool) (Order, CreateOrderError, error)
+	PlaceLimitGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, endTime time.Time, postOnly bool) (Order, CreateOrderError, error)
+	PlaceLimitIOC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal) (Order, CreateOrderError, error)
+	PlaceLimitFOK(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal) (Order, CreateOrderError, error)
+	PlaceStopLimitGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopPrice decimal.Decimal, stopDirection StopDirection) (Order, CreateOrderError, error)
+	PlaceStopLimitGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopPrice decimal.Decimal, stopDirection StopDirection, endTime time.Time) (Order, CreateOrderError, error)
+	CancelOrders(orderIds []string) (map[string]CancelOrderError, error)
+	GetOrder(id string) (Order, error)
+	UpdateOrder(order *Order) error
+}
+
+// OrderService covers everything to do with orders and fills.
+type OrderService interface {
+	OrderPlacer
+	PlaceBracketGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopTriggerPrice decimal.Decimal) (Order, CreateOrderError, error)
+	PlaceBracketGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopTriggerPrice decimal.Decimal, endTime time.Time) (Order, CreateOrderError, error)
+	PreviewOrder(productId string, side Side, orderConfig OrderConfiguration) (OrderPreview, error)
+	CancelAllOrders(productID string) ([]string, map[string]CancelOrderError, error)
+	WaitForFill(ctx context.Context, orderID string) (Order, error)
+	CancelAndConfirm(ctx context.Context, orderID string) (Order, []Fill, error)
+
+	ListOrders(params ListOrdersParameters) (OrderList, error)
+	IterOrders(params ListOrdersParameters) *Iterator[Order]
+	StreamOrders(ctx context.Context, params ListOrdersParameters) (<-chan Order, <-chan error)
+	Orders(ctx context.Context, params ListOrdersParameters) iter.Seq2[Order, error]
+
+	ListFills(params ListFillsParameters) (FillList, error)
+	IterFills(params ListFillsParameters) *Iterator[Fill]
+	StreamFills(ctx context.Context, params ListFillsParameters) (<-chan Fill, <-chan error)
+	Fills(ctx context.Context, params ListFillsParameters) iter.Seq2[Fill, error]
+	ExportFills(w io.Writer, params ListFillsParameters) (int, error)
+}
+
+// MarketDataService gets products and market data. *PublicClient also satisfies it.
+type MarketDataService interface {
+	ListProducts(params ListProductsParameters) (ProductList, error)
+	IterProducts(params ListProductsParameters) *Iterator[Product]
+	StreamProducts(ctx context.Context, params ListProductsParameters) (<-chan Product, <-chan error)
+	Products(ctx context.Context, params ListProductsParameters) iter.Seq2[Product, error]
+	GetProduct(id string) (Product, error)
+	GetProductCandles(id string, start, end time.Time, granularity Granularity) ([]Candle, error)
+	GetMarketTrades(product string, n int) (MarketTrades, error)
+	GetMarketTradesRange(product string, params MarketTradesParameters) (MarketTrades, error)
+	PageMarketTrades(product string, params MarketTradesParameters) *MarketTradePager
+	GetProductBook(productID string, limit int, aggregationIncrement decimal.Decimal) (ProductBook, error)
+}
+
+// CoinbaseTrader covers every API method of *Client.
+type CoinbaseTrader interface {
+	AccountService
+	OrderService
+	MarketDataService
+
+	GetTransactionSummary(params TransactionSummaryParameters) (TransactionSummary, error)
+	GetFeeTierProgress() (FeeTierProgress, error)
+	MovePortfolioFunds(from, to string, amount decimal.Decimal, currency string) error
+	GetPortfolioValue(quote string) (PortfolioValue, error)
+	CostBasisReport(method LotMethod, start, end time.Time) ([]Disposal, error)
+	BackfillCandles(ctx context.Context, id string, start, end time.Time, granularity Granularity, workers int) (<-chan Candle, <-chan error)
+	GetServerTime() (time.Time, error)
+	SyncClock() (time.Duration, error)
+	ClockOffset() time.Duration
+	RateLimit() RateLimitStatus
+	PublicRateLimit() RateLimitStatus
+	RateLimitStats() RateLimitStats
+	PublicRateLimitStats() RateLimitStats
+	Ping(ctx context.Context) error
+	CheckCredentials() error
+	GetKeyPermissions() (KeyPermissions, error)
+	Do(ctx context.Context, method Method, path string, query url.Values, body []byte) ([]byte, *http.Response, error)
+}
+
+var (
+	_ CoinbaseTrader    = (*Client)(nil)
+	_ OrderPlacer       = (*PaperClient)(nil)
+	_ MarketDataService = (*PublicClient)(nil)
+	_ BookSource        = (*Client)(nil)
+	_ BookSource        = (*PublicClient)(nil)
+)
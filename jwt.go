@@ -0,0 +1,123 @@
+package coinbasetrade
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const jwtLifetime = time.Minute * 2 // Coinbase rejects tokens valid for longer than this
+
+// parsedKey caches the private keys so they only need to be decoded once
+type parsedKey struct {
+	mu   sync.Mutex
+	keys map[string]*ecdsa.PrivateKey // by PEM
+}
+
+// usesJWT reports whether the client is configured with a Coinbase Cloud (CDP) key
+func (c *Client) usesJWT() bool {
+	return c.PrivateKey != ""
+}
+
+// buildJWT creates a signed ES256 token for a CDP key. For REST requests uri should be the
+// method, host, and path of the request (i.e. "GET api.coinbase.com/api/v3/brokerage/accounts").
+// Websocket tokens don't include a uri, so pass an empty string.
+func (c *Client) buildJWT(apiKey APIKey, uri string) (token string, err error) {
+	var key *ecdsa.PrivateKey
+	if key, err = c.ecdsaKey(apiKey.PrivateKey); err != nil {
+		return
+	}
+
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+
+	header := map[string]string{
+		"alg":   "ES256",
+		"typ":   "JWT",
+		"kid":   apiKey.KeyName,
+		"nonce": hex.EncodeToString(nonce),
+	}
+
+	now := c.now()
+	claims := map[string]interface{}{
+		"iss": "cdp",
+		"sub": apiKey.KeyName,
+		"nbf": now.Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+	}
+	if uri != "" {
+		claims["uri"] = uri
+	}
+
+	var h, cl []byte
+	if h, err = json.Marshal(header); err != nil {
+		return
+	}
+	if cl, err = json.Marshal(claims); err != nil {
+		return
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(cl)
+	hash := sha256.Sum256([]byte(unsigned))
+
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return
+	}
+
+	// ES256 signatures are the two 32 byte integers concatenated, rather than ASN.1
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	token = unsigned + "." + base64.RawURLEncoding.EncodeToString(sig)
+	return
+}
+
+// ecdsaKey parses a PEM encoded private key, caching the result
+func (c *Client) ecdsaKey(privateKey string) (key *ecdsa.PrivateKey, err error) {
+	c.parsedKey.mu.Lock()
+	defer c.parsedKey.mu.Unlock()
+	if key = c.parsedKey.keys[privateKey]; key != nil {
+		return
+	}
+
+	// keys stored in environment variables often have their newlines escaped
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(privateKey, `\n`, "\n")))
+	if block == nil {
+		err = errors.New("private key is not PEM encoded")
+		return
+	}
+
+	if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+		// also accept PKCS #8, which is what many tools export
+		parsed, perr := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if perr != nil {
+			err = formatError("parse private key", err)
+			return
+		}
+		var ok bool
+		if key, ok = parsed.(*ecdsa.PrivateKey); !ok {
+			err = errors.New("private key is not an EC key")
+			return
+		}
+		err = nil
+	}
+
+	if c.parsedKey.keys == nil {
+		c.parsedKey.keys = map[string]*ecdsa.PrivateKey{}
+	}
+	c.parsedKey.keys[privateKey] = key
+	return
+}
@@ -0,0 +1,115 @@
+package coinbasetrade
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// APIKey is one set of credentials: either a Key and Secret, or a Coinbase Cloud (CDP) KeyName and
+// PrivateKey.
+type APIKey struct {
+	Key    string
+	Secret string
+
+	KeyName    string
+	PrivateKey string
+}
+
+// usesJWT reports whether this is a Coinbase Cloud (CDP) key
+func (k APIKey) usesJWT() bool {
+	return k.PrivateKey != ""
+}
+
+// KeyRotation decides when a client with several keys moves on to the next one.
+type KeyRotation int
+
+const (
+	// RotateOnRateLimit keeps using one key until a request is rate limited, then moves to the
+	// next key and retries straight away.
+	RotateOnRateLimit KeyRotation = iota
+
+	// RotateRoundRobin uses the next key for every request.
+	RotateRoundRobin
+)
+
+// keyRotationState tracks which key is in use
+type keyRotationState struct {
+	mu      sync.Mutex
+	current int // 0 is the client's own key, and n is Keys[n-1]
+}
+
+// primaryKey returns the client's own credentials
+func (c *Client) primaryKey() APIKey {
+	return APIKey{Key: c.Key, Secret: c.Secret, KeyName: c.KeyName, PrivateKey: c.PrivateKey}
+}
+
+// apiKey returns the credentials to sign the next request with
+func (c *Client) apiKey() APIKey {
+	if len(c.Keys) == 0 {
+		return c.primaryKey()
+	}
+
+	c.keyRotation.mu.Lock()
+	defer c.keyRotation.mu.Unlock()
+	n := c.keyRotation.current % (len(c.Keys) + 1)
+	if c.KeyRotation == RotateRoundRobin {
+		c.keyRotation.current = (n + 1) % (len(c.Keys) + 1)
+	}
+
+	if n == 0 {
+		return c.primaryKey()
+	}
+	return c.Keys[n-1]
+}
+
+// rotateKey moves on to the next key after a request was rate limited, and reports whether there
+// is another key to use
+func (c *Client) rotateKey() bool {
+	if len(c.Keys) == 0 {
+		return false
+	}
+	if c.KeyRotation == RotateOnRateLimit {
+		c.keyRotation.mu.Lock()
+		c.keyRotation.current = (c.keyRotation.current + 1) % (len(c.Keys) + 1)
+		c.keyRotation.mu.Unlock()
+	}
+	return true
+}
+
+// KeyPermissions are what the client's API key is allowed to do.
+type KeyPermissions struct {
+	CanView       bool   `json:"can_view"`
+	CanTrade      bool   `json:"can_trade"`
+	CanTransfer   bool   `json:"can_transfer"`
+	PortfolioID   string `json:"portfolio_uuid"` // the portfolio the key is restricted to
+	PortfolioType string `json:"portfolio_type"` // i.e. "DEFAULT"
+}
+
+// GetKeyPermissions returns what the client's API key is allowed to do, and which portfolio it
+// belongs to. With several Keys, the permissions are of whichever key signed the request.
+func (c *Client) GetKeyPermissions() (perms KeyPermissions, err error) {
+	_, err = c.makeRequest(context.Background(), Get, getKeyPermissionsEndpoint, url.Values{}, []byte{}, &perms, nil)
+	return
+}
+
+// Require returns an error (wrapping ErrForbidden) naming the first permission the key is missing,
+// out of the ones asked for, so an application can stop with a clear message at startup:
+//
+//	perms, err := client.GetKeyPermissions()
+//	...
+//	if err = perms.Require(true, true, false); err != nil {
+//		log.Fatal(err) // "API key can't trade: forbidden"
+//	}
+func (p KeyPermissions) Require(view, trade, transfer bool) error {
+	switch {
+	case view && !p.CanView:
+		return fmt.Errorf("API key can't view: %w", ErrForbidden)
+	case trade && !p.CanTrade:
+		return fmt.Errorf("API key can't trade: %w", ErrForbidden)
+	case transfer && !p.CanTransfer:
+		return fmt.Errorf("API key can't transfer: %w", ErrForbidden)
+	}
+	return nil
+}
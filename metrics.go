@@ -0,0 +1,73 @@
+package coinbasetrade
+
+import (
+	"strings"
+	"time"
+)
+
+// Metrics receives measurements from a Client, so they can be exported to a monitoring system. Set
+// one as the Metrics of a ClientConfig; the prommetrics module has one for Prometheus.
+//
+// Endpoints are reported as patterns like "/orders/historical/{id}" rather than with the actual
+// ids, so they can be used as labels.
+type Metrics interface {
+	// ObserveRequest is called after every attempt of a request. The status is 0 if no response
+	// was received.
+	ObserveRequest(method Method, endpoint string, status int, duration time.Duration)
+
+	// ObserveRateLimitWait is called with how long a request waited for the Limiter.
+	ObserveRateLimitWait(endpoint string, wait time.Duration)
+
+	// ObserveRetry is called before a failed request is retried.
+	ObserveRetry(method Method, endpoint string, attempt int)
+
+	// ObserveWebsocketReconnect is called before each attempt to reconnect a websocket.
+	ObserveWebsocketReconnect(attempt int)
+}
+
+// staticEndpoints are the endpoints without ids, some of which look like they have one
+var staticEndpoints = []string{
+	listAccountsEndpoint, createOrderEndpoint, previewOrderEndpoint, cancelOrdersEndpoint,
+	listOrdersEndpoint, listFillsEndpoint, listProductsEndpoint, getProductBookEndpoint,
+	getTransactionSummaryEndpoint, movePortfolioFundsEndpoint, getServerTimeEndpoint,
+	getKeyPermissionsEndpoint,
+}
+
+// idEndpoints are the endpoints with an id in them
+var idEndpoints = []string{
+	getAccountEndpoint, getOrderEndpoint, getProductEndpoint, getProductCandlesEndpoint,
+	getMarketTradesEndpoint,
+}
+
+// endpointPattern replaces the ids in an endpoint with "{id}", to keep the number of labels small
+func endpointPattern(endpoint string) string {
+	prefix := ""
+	if strings.HasPrefix(endpoint, publicMarketPrefix+"/") {
+		prefix, endpoint = publicMarketPrefix, strings.TrimPrefix(endpoint, publicMarketPrefix)
+	}
+
+	for _, e := range staticEndpoints {
+		if endpoint == e {
+			return prefix + endpoint
+		}
+	}
+
+	parts := strings.Split(endpoint, "/")
+	for _, e := range idEndpoints {
+		pattern := strings.Split(e, "/")
+		if len(pattern) != len(parts) {
+			continue
+		}
+		match := true
+		for n := range pattern {
+			if pattern[n] != "%s" && pattern[n] != parts[n] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return prefix + strings.ReplaceAll(e, "%s", "{id}")
+		}
+	}
+	return prefix + endpoint
+}
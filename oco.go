@@ -0,0 +1,175 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OCOPair is a take profit limit order and a stop limit order for the same product, side, and
+// size, where filling one cancels the other.
+type OCOPair struct {
+	ProductID    string
+	Side         Side
+	Size         decimal.Decimal
+	TakeProfitID string
+	StopLossID   string
+}
+
+// OCOResult is the outcome of an OCOPair. Triggered is the final state of the order that was
+// filled (or that was partly filled before it was cancelled or expired), or empty if one of the
+// orders ended without any fills, e.g. because it was cancelled by hand. Sibling is the final
+// state of the other order. If the sibling was also filled before it could be cancelled, its fills
+// are in SiblingFills and the position is larger than intended.
+type OCOResult struct {
+	Pair         OCOPair
+	Triggered    Order
+	Sibling      Order
+	SiblingFills []Fill
+	Err          error // set if the sibling couldn't be cancelled
+}
+
+// OCOManager emulates "one cancels the other" orders, which Coinbase doesn't offer directly. It
+// places both orders of a pair, watches them with an OrderTracker, and once either is finished it
+// cancels the other and calls OnDone. A partly filled order leaves the other in place, so the rest
+// of the position stays protected until the order is completely filled or otherwise ends. Pass
+// user channel messages to Apply, and call Run to poll orders when there is no websocket. It is
+// safe to use from multiple goroutines.
+type OCOManager struct {
+	OnDone        func(OCOResult) // called once for each pair, from its own goroutine
+	CancelTimeout time.Duration   // how long to wait for a sibling to be cancelled (default 1 minute)
+
+	client  *Client
+	tracker *OrderTracker
+	mu      sync.Mutex
+	pairs   map[string]*ocoState // keyed by both order ids
+}
+
+type ocoState struct {
+	pair OCOPair
+	done bool
+}
+
+// NewOCOManager creates an OCOManager that uses this client to place, track, and cancel orders.
+func (c *Client) NewOCOManager(onDone func(OCOResult)) *OCOManager {
+	m := &OCOManager{
+		OnDone:        onDone,
+		CancelTimeout: time.Minute,
+		client:        c,
+		pairs:         make(map[string]*ocoState),
+	}
+	m.tracker = c.NewOrderTracker(m.handle)
+	return m
+}
+
+// Place places a take profit limit order at takeProfitPrice and a stop limit order that triggers
+// at stopPrice with a limit of stopLimitPrice, both good till cancelled. For a sell (closing a
+// long position) the stop is triggered when the price falls to stopPrice, and for a buy when it
+// rises to it. If the second order can't be placed, the first is cancelled.
+func (m *OCOManager) Place(productID string, side Side, size, takeProfitPrice, stopPrice, stopLimitPrice decimal.Decimal) (pair OCOPair, err error) {
+	direction := StopDirectionDown
+	if side == Buy {
+		direction = StopDirectionUp
+	}
+
+	var tp, sl Order
+	if tp, _, err = m.client.PlaceLimitGTC("", productID, side, size, takeProfitPrice, false); err != nil {
+		err = formatError("place take profit order", err)
+		return
+	}
+
+	sl, _, err = m.client.CreateOrder("", productID, side, OrderConfiguration{
+		Type:          StopLimitGTC,
+		BaseSize:      size,
+		LimitPrice:    stopLimitPrice,
+		StopPrice:     stopPrice,
+		StopDirection: direction,
+	})
+	if err != nil {
+		err = formatError("place stop loss order", err)
+		m.client.CancelOrders([]string{tp.ID})
+		return
+	}
+
+	pair = OCOPair{
+		ProductID:    productID,
+		Side:         side,
+		Size:         size,
+		TakeProfitID: tp.ID,
+		StopLossID:   sl.ID,
+	}
+	m.Watch(pair)
+	return
+}
+
+// Watch starts managing a pair of orders that have already been placed.
+func (m *OCOManager) Watch(pair OCOPair) {
+	s := &ocoState{pair: pair}
+	m.mu.Lock()
+	m.pairs[pair.TakeProfitID] = s
+	m.pairs[pair.StopLossID] = s
+	m.mu.Unlock()
+	m.tracker.Track(pair.TakeProfitID, pair.StopLossID)
+}
+
+// Apply takes order updates from a user channel message. Messages from other channels are ignored.
+func (m *OCOManager) Apply(msg WebsocketMessage) error {
+	return m.tracker.Apply(msg)
+}
+
+// Run polls orders that haven't been updated by the websocket recently, until the context is done.
+func (m *OCOManager) Run(ctx context.Context) error {
+	return m.tracker.Run(ctx)
+}
+
+// handle reacts to events from the tracker
+func (m *OCOManager) handle(e OrderEvent) {
+	if e.Type == OrderAccepted {
+		return
+	}
+
+	m.mu.Lock()
+	s, ok := m.pairs[e.Order.ID]
+	if !ok || s.done {
+		m.mu.Unlock()
+		return
+	}
+	if e.Type == OrderPartiallyFilled {
+		// keep the sibling until this order is finished, as it still protects the unfilled size
+		m.mu.Unlock()
+		return
+	}
+	s.done = true
+	delete(m.pairs, s.pair.TakeProfitID)
+	delete(m.pairs, s.pair.StopLossID)
+	m.mu.Unlock()
+
+	sibling := s.pair.StopLossID
+	if e.Order.ID == s.pair.StopLossID {
+		sibling = s.pair.TakeProfitID
+	}
+	m.tracker.Untrack(s.pair.TakeProfitID)
+	m.tracker.Untrack(s.pair.StopLossID)
+
+	res := OCOResult{Pair: s.pair}
+	if e.Type == OrderFilled || e.Order.FilledSize.IsPositive() {
+		res.Triggered = e.Order
+	}
+
+	// cancel the sibling without holding up whoever delivered the event
+	go func() {
+		timeout := m.CancelTimeout
+		if timeout <= 0 {
+			timeout = time.Minute
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		res.Sibling, res.SiblingFills, res.Err = m.client.CancelAndConfirm(ctx, sibling)
+		if m.OnDone != nil {
+			m.OnDone(res)
+		}
+	}()
+}
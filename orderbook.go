@@ -0,0 +1,282 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	Level2Channel Channel = "level2"
+
+	// level2 messages are delivered with a different channel name than the one used to subscribe
+	level2DataChannel Channel = "l2_data"
+
+	checksumDepth = 25 // how many levels on each side are included in the checksum
+)
+
+var (
+	ErrSequenceGap    = errors.New("order book missed a message (sequence gap)")
+	ErrCrossedBook    = errors.New("order book is crossed (best bid is not below best ask)")
+	ErrNoBookSnapshot = errors.New("order book received an update before a snapshot")
+)
+
+// BookLevel is a single price level in an order book.
+type BookLevel struct {
+	Price decimal.Decimal `json:"price"`
+	Size  decimal.Decimal `json:"size"`
+}
+
+// Level2Update is a change to a single price level. A zero NewQuantity means the level was removed.
+type Level2Update struct {
+	Side        string          `json:"side"` // "bid" or "offer"
+	EventTime   time.Time       `json:"event_time"`
+	PriceLevel  decimal.Decimal `json:"price_level"`
+	NewQuantity decimal.Decimal `json:"new_quantity"`
+}
+
+// Level2Event is either a full snapshot of the book or a set of updates to it.
+type Level2Event struct {
+	Type      string         `json:"type"` // "snapshot" or "update"
+	ProductID string         `json:"product_id"`
+	Updates   []Level2Update `json:"updates"`
+}
+
+// Level2Events decodes the events from a level2 channel message.
+func (m WebsocketMessage) Level2Events() (events []Level2Event, err error) {
+	if m.Channel != level2DataChannel {
+		err = fmt.Errorf("cannot decode %s message as level2 events", m.Channel)
+		return
+	}
+
+	if err = json.Unmarshal(m.Events, &events); err != nil {
+		err = formatError("unmarshal level2 events", err)
+	}
+	return
+}
+
+// OrderBook maintains a local copy of the order book for one product from level2 websocket
+// messages. It is safe to read from the book while another goroutine applies messages.
+type OrderBook struct {
+	ProductID string
+
+	mu      sync.RWMutex
+	bids    []BookLevel // sorted highest price first
+	asks    []BookLevel // sorted lowest price first
+	lastSeq int64
+	haveSeq bool
+	synced  bool
+	err     error
+}
+
+// NewOrderBook creates an empty order book for a product. Subscribe to the level2 channel for the
+// same product and pass every message from the websocket to Apply.
+func NewOrderBook(productID string) *OrderBook {
+	return &OrderBook{ProductID: productID}
+}
+
+// Apply updates the book from a websocket message. Messages from other channels are ignored, but
+// they should still be passed in, because the sequence number is shared by every message on the
+// connection and is used to detect dropped messages. Once an error is returned the book is
+// considered corrupted until the next snapshot arrives; resubscribe to get a fresh one.
+// After a reconnect the book waits for the new snapshot that comes with the resubscription.
+func (ob *OrderBook) Apply(msg WebsocketMessage) (err error) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	// a new connection starts its own sequence
+	if msg.Channel == ResyncChannel {
+		ob.haveSeq, ob.synced, ob.err = false, false, nil
+		return
+	}
+	if msg.Channel == GapChannel {
+		return ob.err // the book checks the sequence itself
+	}
+
+	if ob.haveSeq && msg.SequenceNum != ob.lastSeq+1 {
+		ob.err = ErrSequenceGap
+	}
+	ob.lastSeq, ob.haveSeq = msg.SequenceNum, true
+
+	if msg.Channel != level2DataChannel {
+		return ob.err
+	}
+
+	var events []Level2Event
+	if events, err = msg.Level2Events(); err != nil {
+		return
+	}
+
+	for _, e := range events {
+		if e.ProductID != ob.ProductID {
+			continue
+		}
+
+		switch e.Type {
+		case "snapshot":
+			ob.bids, ob.asks = nil, nil
+			ob.synced, ob.err = true, nil
+		case "update":
+			if !ob.synced {
+				ob.err = ErrNoBookSnapshot
+				continue
+			}
+		}
+
+		for _, u := range e.Updates {
+			if u.Side == "bid" {
+				ob.bids = setLevel(ob.bids, u.PriceLevel, u.NewQuantity, true)
+			} else {
+				ob.asks = setLevel(ob.asks, u.PriceLevel, u.NewQuantity, false)
+			}
+		}
+	}
+
+	if ob.err == nil && len(ob.bids) > 0 && len(ob.asks) > 0 && ob.bids[0].Price.GreaterThanOrEqual(ob.asks[0].Price) {
+		ob.err = ErrCrossedBook
+	}
+	return ob.err
+}
+
+// setLevel inserts, updates, or removes a price level, keeping the slice sorted
+func setLevel(levels []BookLevel, price, size decimal.Decimal, descending bool) []BookLevel {
+	i := sort.Search(len(levels), func(i int) bool {
+		if descending {
+			return levels[i].Price.LessThanOrEqual(price)
+		}
+		return levels[i].Price.GreaterThanOrEqual(price)
+	})
+	found := i < len(levels) && levels[i].Price.Equal(price)
+
+	switch {
+	case size.IsZero() && found:
+		return append(levels[:i], levels[i+1:]...)
+	case size.IsZero():
+		return levels
+	case found:
+		levels[i].Size = size
+		return levels
+	}
+
+	levels = append(levels, BookLevel{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = BookLevel{Price: price, Size: size}
+	return levels
+}
+
+// Err returns the reason the book is considered corrupted, or nil if it is in sync.
+func (ob *OrderBook) Err() error {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.err
+}
+
+// Synced reports whether a snapshot has been received and no errors have occurred since.
+func (ob *OrderBook) Synced() bool {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.synced && ob.err == nil
+}
+
+// BestBid returns the highest bid, and false if there are no bids.
+func (ob *OrderBook) BestBid() (level BookLevel, ok bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	if len(ob.bids) == 0 {
+		return
+	}
+	return ob.bids[0], true
+}
+
+// BestAsk returns the lowest ask, and false if there are no asks.
+func (ob *OrderBook) BestAsk() (level BookLevel, ok bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	if len(ob.asks) == 0 {
+		return
+	}
+	return ob.asks[0], true
+}
+
+// Mid returns the price halfway between the best bid and best ask, and false if either side is empty.
+func (ob *OrderBook) Mid() (mid decimal.Decimal, ok bool) {
+	bid, bidOk := ob.BestBid()
+	ask, askOk := ob.BestAsk()
+	if !bidOk || !askOk {
+		return
+	}
+	return bid.Price.Add(ask.Price).Div(decimal.NewFromInt(2)), true
+}
+
+// Depth returns copies of the top n levels on each side of the book. If n is zero or less, every
+// level is returned.
+func (ob *OrderBook) Depth(n int) (bids, asks []BookLevel) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return topLevels(ob.bids, n), topLevels(ob.asks, n)
+}
+
+func topLevels(levels []BookLevel, n int) []BookLevel {
+	if n <= 0 || n > len(levels) {
+		n = len(levels)
+	}
+	out := make([]BookLevel, n)
+	copy(out, levels)
+	return out
+}
+
+// AggregateLevels combines sorted price levels into buckets of the given increment, adding up the
+// size at each level. Prices are rounded up to the bucket boundary if roundUp is set (use this for
+// asks), or down otherwise (for bids). The order of the levels is preserved.
+func AggregateLevels(levels []BookLevel, increment decimal.Decimal, roundUp bool) []BookLevel {
+	if !increment.IsPositive() {
+		return topLevels(levels, 0)
+	}
+
+	var out []BookLevel
+	for _, l := range levels {
+		bucket := l.Price.Div(increment)
+		if roundUp {
+			bucket = bucket.Ceil()
+		} else {
+			bucket = bucket.Floor()
+		}
+		price := bucket.Mul(increment)
+
+		if n := len(out); n > 0 && out[n-1].Price.Equal(price) {
+			out[n-1].Size = out[n-1].Size.Add(l.Size)
+			continue
+		}
+		out = append(out, BookLevel{Price: price, Size: l.Size})
+	}
+	return out
+}
+
+// Checksum returns a CRC32 of the top levels of the book, alternating bid and ask as
+// "price:size" pairs. Two books built from the same feed will have the same checksum, which can be
+// used to compare copies of a book held by different processes.
+func (ob *OrderBook) Checksum() uint32 {
+	bids, asks := ob.Depth(checksumDepth)
+	return checksumLevels(bids, asks)
+}
+
+// checksumLevels returns the checksum of the top levels of sorted bids and asks
+func checksumLevels(bids, asks []BookLevel) uint32 {
+	var parts []string
+	for i := 0; i < checksumDepth; i++ {
+		if i < len(bids) {
+			parts = append(parts, bids[i].Price.String()+":"+bids[i].Size.String())
+		}
+		if i < len(asks) {
+			parts = append(parts, asks[i].Price.String()+":"+asks[i].Size.String())
+		}
+	}
+	return crc32.ChecksumIEEE([]byte(strings.Join(parts, ":")))
+}
@@ -1,11 +1,13 @@
 package coinbasetrade
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"net/url"
+	"sort"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -20,8 +22,11 @@ type (
 	OrderType              string
 	OrderConfigurationType string
 	StopDirection          string
+	OrderSortBy            string
+	MarginType             string
 	CreateOrderError       string
 	CancelOrderError       string
+	PreviewFailureReason   string
 
 	// for fills
 	TradeType          string
@@ -40,6 +45,9 @@ const (
 	Expired       OrderStatus = "EXPIRED"
 	Failed        OrderStatus = "FAILED"
 	UnknownStatus OrderStatus = "UNKNOWN_ORDER_STATUS"
+	Queued        OrderStatus = "QUEUED"
+	CancelQueued  OrderStatus = "CANCEL_QUEUED"
+	EditQueued    OrderStatus = "EDIT_QUEUED"
 
 	GoodUntilDateTime  TimeInForce = "GOOD_UNTIL_DATE_TIME"
 	GoodUntilCancelled TimeInForce = "GOOD_UNTIL_CANCELLED"
@@ -61,10 +69,21 @@ const (
 	MarketIOC                 OrderConfigurationType = "market_market_ioc"
 	LimitGTC                  OrderConfigurationType = "limit_limit_gtc"
 	LimitGTD                  OrderConfigurationType = "limit_limit_gtd"
+	LimitIOC                  OrderConfigurationType = "sor_limit_ioc"
+	LimitFOK                  OrderConfigurationType = "limit_limit_fok"
 	StopLimitGTC              OrderConfigurationType = "stop_limit_stop_limit_gtc"
 	StopLimitGTD              OrderConfigurationType = "stop_limit_stop_limit_gtd"
+	TriggerBracketGTC         OrderConfigurationType = "trigger_bracket_gtc"
+	TriggerBracketGTD         OrderConfigurationType = "trigger_bracket_gtd"
 	UnknownOrderConfiguration OrderConfigurationType = "unknown_order_config_type"
 
+	UnknownSortBy    OrderSortBy = "UNKNOWN_SORT_BY"
+	SortByLimitPrice OrderSortBy = "LIMIT_PRICE"
+	SortByLastFill   OrderSortBy = "LAST_FILL_TIME"
+
+	MarginCross    MarginType = "CROSS"
+	MarginIsolated MarginType = "ISOLATED"
+
 	StopDirectionUp      StopDirection = "STOP_DIRECTION_STOP_UP"
 	StopDirectionDown    StopDirection = "STOP_DIRECTION_STOP_DOWN"
 	UnknownStopDirection StopDirection = "UNKNOWN_STOP_DIRECTION"
@@ -92,6 +111,35 @@ const (
 	CommanderRejectedCancelOrder CancelOrderError = "COMMANDER_REJECTED_CANCEL_ORDER"
 	DuplicateCancelRequest       CancelOrderError = "DUPLICATE_CANCEL_REQUEST"
 
+	UnknownPreviewFailureReason           PreviewFailureReason = "UNKNOWN_PREVIEW_FAILURE_REASON"
+	PreviewMissingCommissionRate          PreviewFailureReason = "PREVIEW_MISSING_COMMISSION_RATE"
+	PreviewInvalidSide                    PreviewFailureReason = "PREVIEW_INVALID_SIDE"
+	PreviewInvalidOrderConfig             PreviewFailureReason = "PREVIEW_INVALID_ORDER_CONFIG"
+	PreviewInvalidProductID               PreviewFailureReason = "PREVIEW_INVALID_PRODUCT_ID"
+	PreviewInvalidSizePrecision           PreviewFailureReason = "PREVIEW_INVALID_SIZE_PRECISION"
+	PreviewInvalidPricePrecision          PreviewFailureReason = "PREVIEW_INVALID_PRICE_PRECISION"
+	PreviewMissingProductPriceBook        PreviewFailureReason = "PREVIEW_MISSING_PRODUCT_PRICE_BOOK"
+	PreviewInvalidLedgerBalance           PreviewFailureReason = "PREVIEW_INVALID_LEDGER_BALANCE"
+	PreviewInsufficientLedgerBalance      PreviewFailureReason = "PREVIEW_INSUFFICIENT_LEDGER_BALANCE"
+	PreviewInvalidLimitPricePostOnly      PreviewFailureReason = "PREVIEW_INVALID_LIMIT_PRICE_POST_ONLY"
+	PreviewInvalidLimitPrice              PreviewFailureReason = "PREVIEW_INVALID_LIMIT_PRICE"
+	PreviewInvalidNoLiquidity             PreviewFailureReason = "PREVIEW_INVALID_NO_LIQUIDITY"
+	PreviewInsufficientFund               PreviewFailureReason = "PREVIEW_INSUFFICIENT_FUND"
+	PreviewInvalidCommissionConfiguration PreviewFailureReason = "PREVIEW_INVALID_COMMISSION_CONFIGURATION"
+	PreviewInvalidStopPrice               PreviewFailureReason = "PREVIEW_INVALID_STOP_PRICE"
+	PreviewInvalidBaseSizeTooLarge        PreviewFailureReason = "PREVIEW_INVALID_BASE_SIZE_TOO_LARGE"
+	PreviewInvalidBaseSizeTooSmall        PreviewFailureReason = "PREVIEW_INVALID_BASE_SIZE_TOO_SMALL"
+	PreviewInvalidQuoteSizePrecision      PreviewFailureReason = "PREVIEW_INVALID_QUOTE_SIZE_PRECISION"
+	PreviewInvalidQuoteSizeTooLarge       PreviewFailureReason = "PREVIEW_INVALID_QUOTE_SIZE_TOO_LARGE"
+	PreviewInvalidQuoteSizeTooSmall       PreviewFailureReason = "PREVIEW_INVALID_QUOTE_SIZE_TOO_SMALL"
+	PreviewInvalidPriceTooLarge           PreviewFailureReason = "PREVIEW_INVALID_PRICE_TOO_LARGE"
+	PreviewBreachedPriceLimit             PreviewFailureReason = "PREVIEW_BREACHED_PRICE_LIMIT"
+	PreviewBreachedAccountPositionLimit   PreviewFailureReason = "PREVIEW_BREACHED_ACCOUNT_POSITION_LIMIT"
+	PreviewBreachedCompanyPositionLimit   PreviewFailureReason = "PREVIEW_BREACHED_COMPANY_POSITION_LIMIT"
+	PreviewInvalidMarginHealth            PreviewFailureReason = "PREVIEW_INVALID_MARGIN_HEALTH"
+	PreviewRiskProxyFailure               PreviewFailureReason = "PREVIEW_RISK_PROXY_FAILURE"
+	PreviewUntradableFCMAccountStatus     PreviewFailureReason = "PREVIEW_UNTRADABLE_FCM_ACCOUNT_STATUS"
+
 	TradeFill       = "FILL"
 	TradeReversal   = "REVERSAL"
 	TradeCorrection = "CORRECTION"
@@ -102,48 +150,150 @@ const (
 	LiquidityUnknown = "UNKNOWN_LIQUIDITY_INDICATOR"
 )
 
+// ErrInsufficientFunds matches orders rejected for either INSUFFICIENT_FUND or INSUFFICIENT_FUNDS,
+// both of which are returned by the API.
+var ErrInsufficientFunds error = InsufficientFunds
+
+func (e CreateOrderError) Error() string {
+	return string(e)
+}
+
+func (e CancelOrderError) Error() string {
+	return string(e)
+}
+
+func (e PreviewFailureReason) Error() string {
+	return string(e)
+}
+
+// CreateOrderFailure is the error returned when the server rejects a new order. Use errors.Is with
+// any CreateOrderError or PreviewFailureReason value to check the reason, or errors.As to get the
+// details.
+type CreateOrderFailure struct {
+	Reason               CreateOrderError
+	Details              string
+	PreviewFailureReason PreviewFailureReason // set if the order failed the server's preview checks
+}
+
+func (e *CreateOrderFailure) Error() string {
+	if e.Details == "" {
+		return string(e.Reason)
+	}
+	return string(e.Reason) + ": " + e.Details
+}
+
+// Is reports whether the order was rejected for the target reason
+func (e *CreateOrderFailure) Is(target error) bool {
+	if p, ok := target.(PreviewFailureReason); ok {
+		return p == e.PreviewFailureReason
+	}
+
+	t, ok := target.(CreateOrderError)
+	if !ok {
+		return false
+	}
+	if t == e.Reason {
+		return true
+	}
+	insufficient := func(r CreateOrderError) bool { return r == InsufficientFund || r == InsufficientFunds }
+	return insufficient(t) && insufficient(e.Reason)
+}
+
+// CancelOrdersFailure is the error returned when one or more orders could not be cancelled. Use
+// errors.Is with any CancelOrderError value to check if any order failed for that reason.
+type CancelOrdersFailure struct {
+	Failures map[string]CancelOrderError // keyed by order id
+}
+
+func (e *CancelOrdersFailure) Error() string {
+	return "one or more orders were not cancelled successfully"
+}
+
+// Is reports whether any order failed to cancel for the target reason
+func (e *CancelOrdersFailure) Is(target error) bool {
+	t, ok := target.(CancelOrderError)
+	if !ok {
+		return false
+	}
+	for _, v := range e.Failures {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
 // Order represents the status of an order that has been placed.
 // NOTE: As of 12/2022, "reject reason" doesn't seem to have a very obvious use, so
 // it is left as a string for now.
 type Order struct {
 	// used by ListOrders
-	ID                 string             `json:"order_id,omitempty"`
-	Product            string             `json:"product_id"`
-	UserID             string             `json:"user_id,omitempty"`
-	OrderConfiguration OrderConfiguration `json:"-"`
-	// OrderConfiguration   OrderConfiguration `json:"order_configuration"`
-	Side                 Side            `json:"side"`
-	ClientOrderID        string          `json:"client_order_id"`
-	Status               string          `json:"status,omitempty"`
-	TimeInForce          TimeInForce     `json:"time_in_force,omitempty"`
-	CreatedTime          time.Time       `json:"created_time,omitempty"`
-	CompletionPercentage decimal.Decimal `json:"completion_percentage,omitempty"`
-	FilledSize           decimal.Decimal `json:"filled_size,omitempty"`
-	AverageFilledPrice   decimal.Decimal `json:"average_filled_price,omitempty"`
-	Fee                  string          `json:"fee,omitempty"`
-	NumberOfFills        decimal.Decimal `json:"number_of_fills,omitempty"`
-	FilledValue          decimal.Decimal `json:"filled_value,omitempty"`
-	PendingCancel        bool            `json:"pending_cancel,omitempty"`
-	SizeInQuote          bool            `json:"size_in_quote,omitempty"`
-	TotalFees            decimal.Decimal `json:"total_fees,omitempty"`
-	SizeInclusiveOfFees  bool            `json:"size_inclusive_of_fees,omitempty"`
-	TotalValueAfterFees  decimal.Decimal `json:"total_value_after_fees,omitempty"`
-	TriggerStatus        TriggerStatus   `json:"trigger_status,omitempty"`
-	Type                 OrderType       `json:"order_type,omitempty"`
-	RejectReason         string          `json:"reject_reason,omitempty"`
-	Settled              bool            `json:"settled,omitempty"`
-	ProductType          ProductType     `json:"product_type,omitempty"`
-	OutstandingHold      decimal.Decimal `json:"outstanding_hold_amount"`
+	ID                    string             `json:"order_id,omitempty"`
+	Product               string             `json:"product_id"`
+	UserID                string             `json:"user_id,omitempty"`
+	OrderConfiguration    OrderConfiguration `json:"order_configuration"`
+	Side                  Side               `json:"side"`
+	ClientOrderID         string             `json:"client_order_id"`
+	Status                OrderStatus        `json:"status,omitempty"`
+	TimeInForce           TimeInForce        `json:"time_in_force,omitempty"`
+	CreatedTime           time.Time          `json:"created_time,omitempty"`
+	CompletionPercentage  decimal.Decimal    `json:"completion_percentage,omitempty"`
+	FilledSize            decimal.Decimal    `json:"filled_size,omitempty"`
+	AverageFilledPrice    decimal.Decimal    `json:"average_filled_price,omitempty"`
+	Fee                   string             `json:"fee,omitempty"`
+	NumberOfFills         decimal.Decimal    `json:"number_of_fills,omitempty"`
+	FilledValue           decimal.Decimal    `json:"filled_value,omitempty"`
+	PendingCancel         bool               `json:"pending_cancel,omitempty"`
+	SizeInQuote           bool               `json:"size_in_quote,omitempty"`
+	TotalFees             decimal.Decimal    `json:"total_fees,omitempty"`
+	SizeInclusiveOfFees   bool               `json:"size_inclusive_of_fees,omitempty"`
+	TotalValueAfterFees   decimal.Decimal    `json:"total_value_after_fees,omitempty"`
+	TriggerStatus         TriggerStatus      `json:"trigger_status,omitempty"`
+	Type                  OrderType          `json:"order_type,omitempty"`
+	RejectReason          string             `json:"reject_reason,omitempty"`
+	Settled               bool               `json:"settled,omitempty"`
+	ProductType           ProductType        `json:"product_type,omitempty"`
+	OutstandingHold       decimal.Decimal    `json:"outstanding_hold_amount"`
+	EditHistory           []OrderEdit        `json:"edit_history,omitempty"`
+	LastFillTime          time.Time          `json:"last_fill_time"`
+	OriginatingOrderID    string             `json:"originating_order_id,omitempty"` // the order this one was created from, e.g. the stop order of a bracket
+	AttachedOrderID       string             `json:"attached_order_id,omitempty"`    // an order attached to this one when it was placed
+	SelfTradePreventionID string             `json:"self_trade_prevention_id,omitempty"`
 
 	// used by GetOrder
 	RejectMessage string `json:"reject_message,omitempty"`
 	CancelMessage string `json:"cancel_message,omitempty"`
+
+	Raw json.RawMessage `json:"-"` // the order as it was received, if the client's KeepRaw is set
 }
 
-// OrderConfiguration includes all the possible settings for all order types. Due to how the API
-// works, only one value is added to the OrderConfiguration map in the Order struct above, and the key
-// is set to the type of order. Use GetOrderConfiguration and SetOrderConfiguration instead of accesing
-// the map directly.
+// OrderEdit is a change that was made to an open order's price or size.
+type OrderEdit struct {
+	Price                  decimal.Decimal `json:"price"`
+	Size                   decimal.Decimal `json:"size"`
+	ReplaceAcceptTimestamp time.Time       `json:"replace_accept_timestamp"`
+}
+
+// UnmarshalJSON allows every field to be empty.
+func (e *OrderEdit) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Price                  string `json:"price"`
+		Size                   string `json:"size"`
+		ReplaceAcceptTimestamp string `json:"replace_accept_timestamp"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	e.Price = parseOptionalDecimal(aux.Price)
+	e.Size = parseOptionalDecimal(aux.Size)
+	e.ReplaceAcceptTimestamp = parseOptionalTime(aux.ReplaceAcceptTimestamp)
+	return nil
+}
+
+// OrderConfiguration includes all the possible settings for all order types. The API sends it as
+// an object with a single key, which is the type of order, holding the settings; it is decoded
+// from that form, with Type set from the key.
 type OrderConfiguration struct {
 	Type          OrderConfigurationType `json:"-"`
 	QuoteSize     decimal.Decimal        `json:"quote_size,omitempty"`
@@ -153,11 +303,66 @@ type OrderConfiguration struct {
 	StopDirection StopDirection          `json:"stop_direction,omitempty"`
 	EndTime       time.Time              `json:"-"`
 	PostOnly      bool                   `json:"post_only,omitempty"`
+
+	// for bracket orders, the price at which the stop limit order attached to the limit order is triggered
+	StopTriggerPrice decimal.Decimal `json:"stop_trigger_price,omitempty"`
+}
+
+// UnmarshalJSON decodes the order configuration from the keyed form the API uses, e.g.
+// {"limit_limit_gtc": {"base_size": "1", ...}}. Empty values are allowed.
+func (oc *OrderConfiguration) UnmarshalJSON(data []byte) error {
+	var keyed map[string]struct {
+		QuoteSize        string        `json:"quote_size"`
+		BaseSize         string        `json:"base_size"`
+		LimitPrice       string        `json:"limit_price"`
+		StopPrice        string        `json:"stop_price"`
+		StopDirection    StopDirection `json:"stop_direction"`
+		EndTime          string        `json:"end_time"`
+		PostOnly         bool          `json:"post_only"`
+		StopTriggerPrice string        `json:"stop_trigger_price"`
+	}
+	if err := json.Unmarshal(data, &keyed); err != nil {
+		return err
+	}
+
+	*oc = OrderConfiguration{}
+	for k, v := range keyed {
+		*oc = OrderConfiguration{
+			Type:             OrderConfigurationType(k),
+			QuoteSize:        parseOptionalDecimal(v.QuoteSize),
+			BaseSize:         parseOptionalDecimal(v.BaseSize),
+			LimitPrice:       parseOptionalDecimal(v.LimitPrice),
+			StopPrice:        parseOptionalDecimal(v.StopPrice),
+			StopDirection:    v.StopDirection,
+			EndTime:          parseOptionalTime(v.EndTime),
+			PostOnly:         v.PostOnly,
+			StopTriggerPrice: parseOptionalDecimal(v.StopTriggerPrice),
+		}
+		break
+	}
+	return nil
 }
 
-// toMap builds a map of strings from the order config for use with the api
-func (oc OrderConfiguration) toMap() (m map[string]string) {
-	m = make(map[string]string)
+// MarshalJSON encodes the order configuration in the keyed form the API uses, so it can be sent
+// in requests, and saved and decoded again without losing anything. If Type isn't set, it is
+// worked out from the values; a configuration with nothing set is encoded as an empty object.
+func (oc OrderConfiguration) MarshalJSON() ([]byte, error) {
+	values := oc.toMap()
+	if oc.Type == "" && len(values) == 0 {
+		return []byte("{}"), nil
+	}
+
+	t := oc.Type
+	if t == "" {
+		t = oc.getType()
+	}
+	return json.Marshal(map[string]map[string]interface{}{string(t): values})
+}
+
+// toMap builds a map of the values that are set in the order config, in the form the api expects.
+// Everything is a string except for post_only, which is a boolean.
+func (oc OrderConfiguration) toMap() (m map[string]interface{}) {
+	m = make(map[string]interface{})
 	if !oc.QuoteSize.IsZero() {
 		m["quote_size"] = oc.QuoteSize.String()
 	}
@@ -173,25 +378,42 @@ func (oc OrderConfiguration) toMap() (m map[string]string) {
 	if oc.StopDirection != "" {
 		m["stop_direction"] = string(oc.StopDirection)
 	}
+	if !oc.StopTriggerPrice.IsZero() {
+		m["stop_trigger_price"] = oc.StopTriggerPrice.String()
+	}
 	if !oc.EndTime.IsZero() {
 		m["end_time"] = timeToString(oc.EndTime)
 	}
 	if oc.PostOnly {
-		m["post_only"] = "true"
+		m["post_only"] = true
 	}
 	return
 }
 
-// getType returns the order configuration type, based on the values that are set
+// validate catches combinations of settings that the server would reject
+func (oc OrderConfiguration) validate() error {
+	if oc.PostOnly && (oc.Type == LimitIOC || oc.Type == LimitFOK || oc.Type == MarketIOC) {
+		return &ValidationError{Reason: fmt.Sprintf("post only can't be used with %s orders", oc.Type)}
+	}
+	return nil
+}
+
+// getType returns the order configuration type, based on the values that are set. Limit IOC and
+// FOK orders have the same values as limit GTC orders, so they must be set explicitly.
 func (oc OrderConfiguration) getType() OrderConfigurationType {
 	// classify order config
 	gtd := !oc.EndTime.IsZero()
 	stop := !oc.StopPrice.IsZero()
 	limit := !oc.LimitPrice.IsZero()
+	bracket := !oc.StopTriggerPrice.IsZero()
 
 	switch {
 	case !limit: // if no limit price, it's a market order
 		return MarketIOC
+	case bracket && !gtd: // if there is a stop trigger price, it's a bracket order
+		return TriggerBracketGTC
+	case bracket:
+		return TriggerBracketGTD
 	case !gtd && !stop: // if no end date or stop price, it's a limit gtc
 		return LimitGTC
 	case gtd && !stop: // if there is an end date but no stop price, it's a limit gtd
@@ -203,23 +425,79 @@ func (oc OrderConfiguration) getType() OrderConfigurationType {
 	}
 }
 
+// OrderOptions are optional settings for placing an order, for futures and perpetuals, and for
+// users with more than one portfolio. Zero values are left out of the request.
+type OrderOptions struct {
+	Leverage          decimal.Decimal
+	MarginType        MarginType
+	RetailPortfolioID string // the portfolio to place the order in, instead of the default one
+
+	// Orders with the same self trade prevention id won't be matched against each other, so
+	// orders from separate strategies can be grouped together.
+	SelfTradePreventionID string
+}
+
 // CreateOrder will submit your raw order details and return a populated `Order` object. You must include a valid
 // `OrderConfiguration` based on the type of order you wish to place. If the combination of data populated in
 // the order config is invalid, the server will return an error. It is recommended to use one of the helper functions
 // instead (PlaceMarketIOC, PlaceLimitGTC, etc)
+//
+// If the order is rejected, errorType is set to the reason and err is a *CreateOrderFailure, so
+// you can also check the reason with errors.Is(err, coinbasetrade.ErrInsufficientFunds), etc.
 func (c *Client) CreateOrder(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration) (order Order, errorType CreateOrderError, err error) {
+	return c.CreateOrderWithOptions(clientOrderId, productId, side, orderConfig, OrderOptions{})
+}
 
-	// if no client id is specified, use unix time in milliseconds
+// CreateOrderWithOptions works like CreateOrder, and also sends the given options with the order.
+func (c *Client) CreateOrderWithOptions(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration, opts OrderOptions) (order Order, errorType CreateOrderError, err error) {
+
+	// if no client id is specified, generate one
 	if clientOrderId == "" {
-		clientOrderId = fmt.Sprintf("%d", time.Now().UnixMilli())
+		clientOrderId = c.newClientOrderID()
+	}
+
+	if orderConfig, err = c.prepareOrder(productId, orderConfig); err != nil {
+		err = formatError("create order", err)
+		return
+	}
+
+	// if there is an idempotency store, don't send an order that has already been placed
+	if c.Submissions != nil {
+		sub := OrderSubmission{
+			ClientOrderID:      clientOrderId,
+			ProductID:          productId,
+			Side:               side,
+			OrderConfiguration: orderConfig,
+			Options:            opts,
+		}
+		var done bool
+		if order, done, err = c.beginSubmission(sub); done || err != nil {
+			return
+		}
+		defer func() { c.finishSubmission(sub, order, errorType, err) }()
 	}
 
 	wrapper := struct {
-		ClientOrderID      string                       `json:"client_order_id"`
-		ProductID          string                       `json:"product_id"`
-		Side               Side                         `json:"side"`
-		OrderConfiguration map[string]map[string]string `json:"order_configuration"`
-	}{clientOrderId, productId, side, map[string]map[string]string{string(orderConfig.Type): orderConfig.toMap()}}
+		ClientOrderID         string             `json:"client_order_id"`
+		ProductID             string             `json:"product_id"`
+		Side                  Side               `json:"side"`
+		OrderConfiguration    OrderConfiguration `json:"order_configuration"`
+		Leverage              string             `json:"leverage,omitempty"`
+		MarginType            MarginType         `json:"margin_type,omitempty"`
+		RetailPortfolioID     string             `json:"retail_portfolio_id,omitempty"`
+		SelfTradePreventionID string             `json:"self_trade_prevention_id,omitempty"`
+	}{
+		ClientOrderID:         clientOrderId,
+		ProductID:             productId,
+		Side:                  side,
+		OrderConfiguration:    orderConfig,
+		MarginType:            opts.MarginType,
+		RetailPortfolioID:     opts.RetailPortfolioID,
+		SelfTradePreventionID: opts.SelfTradePreventionID,
+	}
+	if !opts.Leverage.IsZero() {
+		wrapper.Leverage = opts.Leverage.String()
+	}
 
 	var payload []byte
 	if payload, err = json.Marshal(wrapper); err != nil {
@@ -227,21 +505,18 @@ func (c *Client) CreateOrder(clientOrderId string, productId string, side Side,
 		return
 	}
 
-	// convert post_only to boolean, because it's the only item the API expexcts to not be a string. This could cause an error
-	// if client id is set to a string of "true", but if that's the case you have bigger problems (i.e. not my fault)
-	payload = bytes.ReplaceAll(payload, []byte(`"true"`), []byte(`true`))
-
 	response := struct {
-		Success     bool                          `json:"success"`
-		OrderID     string                        `json:"order_id"`
-		OrderConfig map[string]OrderConfiguration `json:"order_configuration"`
+		Success     bool               `json:"success"`
+		OrderID     string             `json:"order_id"`
+		OrderConfig OrderConfiguration `json:"order_configuration"`
 		Error       struct {
-			Error   CreateOrderError `json:"error"`
-			Details string           `json:"error_details"`
+			Error                CreateOrderError     `json:"error"`
+			Details              string               `json:"error_details"`
+			PreviewFailureReason PreviewFailureReason `json:"preview_failure_reason"`
 		} `json:"error_response"`
 	}{}
 
-	if _, err = c.makeRequest(Post, createOrderEndpoint, url.Values{}, payload, &response, nil); err != nil {
+	if _, err = c.makeRequest(context.Background(), Post, createOrderEndpoint, url.Values{}, payload, &response, nil); err != nil {
 		err = formatError("api connection error", err)
 		return
 	}
@@ -249,19 +524,91 @@ func (c *Client) CreateOrder(clientOrderId string, productId string, side Side,
 	if response.Success {
 		order = Order{
 			ID:                 response.OrderID,
+			Product:            productId,
+			ClientOrderID:      clientOrderId,
 			Side:               side,
-			OrderConfiguration: response.OrderConfig[string(orderConfig.getType())],
+			OrderConfiguration: response.OrderConfig,
+		}
+		if order.OrderConfiguration.Type == "" { // not echoed back
+			order.OrderConfiguration = orderConfig
 		}
 		return
 	}
 
 	errorType = response.Error.Error
-	err = errors.New(response.Error.Details)
+	err = &CreateOrderFailure{
+		Reason:               errorType,
+		Details:              response.Error.Details,
+		PreviewFailureReason: response.Error.PreviewFailureReason,
+	}
+	return
+}
+
+// PlaceOrder works like CreateOrderWithOptions, but returns a single error. If the server rejects
+// the order, it is a *CreateOrderFailure with the reason, which can be checked with errors.Is or
+// errors.As:
+//
+//	var failure *coinbasetrade.CreateOrderFailure
+//	if errors.As(err, &failure) {
+//		log.Println(failure.Reason, failure.PreviewFailureReason, failure.Details)
+//	}
+func (c *Client) PlaceOrder(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration, opts OrderOptions) (order Order, err error) {
+	order, _, err = c.CreateOrderWithOptions(clientOrderId, productId, side, orderConfig, opts)
+	return
+}
+
+// OrderPreview shows what would happen if an order was placed. If the order would be rejected,
+// Errors contains the reasons why.
+type OrderPreview struct {
+	PreviewID       string                 `json:"preview_id"`
+	OrderTotal      decimal.Decimal        `json:"order_total"`
+	CommissionTotal decimal.Decimal        `json:"commission_total"`
+	Slippage        decimal.Decimal        `json:"slippage"`
+	QuoteSize       decimal.Decimal        `json:"quote_size"`
+	BaseSize        decimal.Decimal        `json:"base_size"`
+	BestBid         decimal.Decimal        `json:"best_bid"`
+	BestAsk         decimal.Decimal        `json:"best_ask"`
+	IsMax           bool                   `json:"is_max"`
+	Errors          []PreviewFailureReason `json:"errs"`
+	Warnings        []string               `json:"warning"`
+}
+
+// PreviewOrder takes the same order details as CreateOrder, and returns the projected fees,
+// slippage, and total for the order without placing it. If OrderConfiguration.Type isn't set, it
+// is determined from the values that are.
+func (c *Client) PreviewOrder(productId string, side Side, orderConfig OrderConfiguration) (preview OrderPreview, err error) {
+	if orderConfig.Type == "" {
+		orderConfig.Type = orderConfig.getType()
+	}
+	if orderConfig, err = c.prepareOrder(productId, orderConfig); err != nil {
+		err = formatError("preview order", err)
+		return
+	}
+
+	wrapper := struct {
+		ProductID          string             `json:"product_id"`
+		Side               Side               `json:"side"`
+		OrderConfiguration OrderConfiguration `json:"order_configuration"`
+	}{productId, side, orderConfig}
+
+	var payload []byte
+	if payload, err = json.Marshal(wrapper); err != nil {
+		err = formatError("preview order", err)
+		return
+	}
+
+	_, err = c.makeRequest(context.Background(), Post, previewOrderEndpoint, url.Values{}, payload, &preview, nil)
 	return
 }
 
 // CancelOrders takes a slice of order ids to cancel, and returns a map of potential errors for each order id.
+// If any order was not cancelled, err is a *CancelOrdersFailure containing the same map.
 func (c *Client) CancelOrders(orderIds []string) (cancelErrors map[string]CancelOrderError, err error) {
+	return c.cancelOrders(context.Background(), orderIds)
+}
+
+// cancelOrders works like CancelOrders, with ctx used for the request
+func (c *Client) cancelOrders(ctx context.Context, orderIds []string) (cancelErrors map[string]CancelOrderError, err error) {
 	wrapper := struct {
 		Orders []string `json:"order_ids"`
 	}{orderIds}
@@ -280,7 +627,7 @@ func (c *Client) CancelOrders(orderIds []string) (cancelErrors map[string]Cancel
 		} `json:"results"`
 	}{}
 
-	if _, err = c.makeRequest(Post, cancelOrdersEndpoint, url.Values{}, payload, &response, nil); err != nil {
+	if _, err = c.makeRequest(ctx, Post, cancelOrdersEndpoint, url.Values{}, payload, &response, nil); err != nil {
 		err = formatError("api connection error", err)
 		return
 	}
@@ -293,14 +640,86 @@ func (c *Client) CancelOrders(orderIds []string) (cancelErrors map[string]Cancel
 		cancelErrors[v.ID] = v.Error
 	}
 	if len(cancelErrors) > 0 {
-		err = errors.New("one or more orders were not cancelled successfully")
+		err = &CancelOrdersFailure{Failures: cancelErrors}
+	}
+	return
+}
+
+// maxCancelBatch is the most orders that can be cancelled in one request
+const maxCancelBatch = 100
+
+// CancelAllOrders cancels every open order for a product, or for all products if productID is
+// empty. The open orders are all listed first, then cancelled in batches. It returns the ids of
+// the orders that were cancelled, and the reasons any others weren't; if there were any of those,
+// err is a *CancelOrdersFailure containing the same map.
+func (c *Client) CancelAllOrders(productID string) (cancelled []string, cancelErrors map[string]CancelOrderError, err error) {
+	var l OrderList
+	if l, err = c.ListOrders(ListOrdersParameters{Product: productID, Status: []OrderStatus{Open}}); err != nil {
+		return
+	}
+
+	var ids []string
+	for l.Next() {
+		for _, o := range l.Orders {
+			ids = append(ids, o.ID)
+		}
+		if err = l.NextPage(); err != nil {
+			return
+		}
+	}
+
+	cancelErrors = make(map[string]CancelOrderError)
+	for start := 0; start < len(ids); start += maxCancelBatch {
+		batch := ids[start:]
+		if len(batch) > maxCancelBatch {
+			batch = batch[:maxCancelBatch]
+		}
+
+		var batchErrors map[string]CancelOrderError
+		if batchErrors, err = c.CancelOrders(batch); err != nil {
+			var failure *CancelOrdersFailure
+			if !errors.As(err, &failure) {
+				return
+			}
+			err = nil
+		}
+
+		for _, id := range batch {
+			if reason, failed := batchErrors[id]; failed {
+				cancelErrors[id] = reason
+			} else {
+				cancelled = append(cancelled, id)
+			}
+		}
+	}
+
+	if len(cancelErrors) > 0 {
+		err = &CancelOrdersFailure{Failures: cancelErrors}
 	}
 	return
 }
 
 type OrderList struct {
 	Orders []Order `json:"orders"`
-	Pagination
+	Pagination[ListOrdersParameters, Order]
+}
+
+// NextPage replaces the orders in the list with the next page of results, or appends the page to
+// them if Accumulate is set.
+func (l *OrderList) NextPage() error {
+	_, err := l.FetchPage()
+	return err
+}
+
+// FetchPage works like NextPage, and also returns the orders in the page that was fetched. It returns
+// no orders once there are no more pages.
+func (l *OrderList) FetchPage() ([]Order, error) {
+	return l.nextPage(context.Background(), &l.Orders)
+}
+
+// All returns the orders in the current page and every page after it, up to the given limits.
+func (l *OrderList) All(ctx context.Context, limits AllLimits) ([]Order, error) {
+	return l.all(ctx, limits, &l.Orders)
 }
 
 type ListOrdersParameters struct {
@@ -311,28 +730,62 @@ type ListOrdersParameters struct {
 	StartDate          time.Time     `cbt:"start_date"`
 	EndDate            time.Time     `cbt:"end_date"`
 	UserNativeCurrency string        `cbt:"user_native_currency"`
-	ProductType        string        `cbt:"product_type"`
+	ProductType        ProductType   `cbt:"product_type"`
 	Limit              int           `cbt:"limit"`
+
+	// for futures orders
+	ContractExpiryType ContractExpiryType `cbt:"contract_expiry_type"`
+
+	SortBy            OrderSortBy   `cbt:"sort_by"`
+	AssetFilters      []string      `cbt:"asset_filters"` // only orders for products with these base currencies
+	TimeInForces      []TimeInForce `cbt:"time_in_forces"`
+	RetailPortfolioID string        `cbt:"retail_portfolio_id"`
+	Cursor            string        `cbt:"cursor"` // start listing from a cursor saved from an earlier list
 }
 
 // ListOrders returns a list of orders based on the parameters you include.
 func (c *Client) ListOrders(params ListOrdersParameters) (l OrderList, err error) {
+	l.Pagination = Pagination[ListOrdersParameters, Order]{pager: c.ordersPager(params)}
+
+	err = l.NextPage()
+	return
+}
+
+// IterOrders returns an iterator over every order matching the parameters, fetching pages as
+// needed.
+func (c *Client) IterOrders(params ListOrdersParameters) *Iterator[Order] {
+	return newIterator(c.ordersPager(params))
+}
+
+// StreamOrders sends every order matching the parameters on a channel as the pages are fetched. See
+// Iterator.Stream.
+func (c *Client) StreamOrders(ctx context.Context, params ListOrdersParameters) (<-chan Order, <-chan error) {
+	return c.IterOrders(params).Stream(ctx)
+}
+
+// Orders returns every order matching the parameters as a sequence for use with range, fetching pages as needed:
+//
+//	for o, err := range client.Orders(ctx, params) {
+//		...
+//	}
+func (c *Client) Orders(ctx context.Context, params ListOrdersParameters) iter.Seq2[Order, error] {
+	return c.IterOrders(params).Seq(ctx)
+}
+
+func (c *Client) ordersPager(params ListOrdersParameters) pager[ListOrdersParameters, Order] {
 	// this endpoint has no default limit, so we must ensure there is one
 	if params.Limit <= 0 {
 		params.Limit = 50
 	}
 
-	l.Pagination = Pagination{
-		client:     c,
-		parent:     &l,
-		parameters: params,
+	return pager[ListOrdersParameters, Order]{
+		client: c,
+		params: params,
 
 		method:   Get,
 		endpoint: listOrdersEndpoint,
+		key:      "orders",
 	}
-
-	err = l.NextPage()
-	return
 }
 
 type Fill struct {
@@ -350,11 +803,31 @@ type Fill struct {
 	SizeInQuote        bool               `json:"size_in_quote"`
 	UserID             string             `json:"user_id"`
 	Side               Side               `json:"side"`
+
+	Raw json.RawMessage `json:"-"` // the fill as it was received, if the client's KeepRaw is set
 }
 
 type FillList struct {
 	Fills []Fill
-	Pagination
+	Pagination[ListFillsParameters, Fill]
+}
+
+// NextPage replaces the fills in the list with the next page of results, or appends the page to
+// them if Accumulate is set.
+func (l *FillList) NextPage() error {
+	_, err := l.FetchPage()
+	return err
+}
+
+// FetchPage works like NextPage, and also returns the fills in the page that was fetched. It returns
+// no fills once there are no more pages.
+func (l *FillList) FetchPage() ([]Fill, error) {
+	return l.nextPage(context.Background(), &l.Fills)
+}
+
+// All returns the fills in the current page and every page after it, up to the given limits.
+func (l *FillList) All(ctx context.Context, limits AllLimits) ([]Fill, error) {
+	return l.all(ctx, limits, &l.Fills)
 }
 
 type ListFillsParameters struct {
@@ -363,58 +836,84 @@ type ListFillsParameters struct {
 	StartSequenceTime time.Time `cbt:"start_sequence_timestamp"`
 	EndSequenceTime   time.Time `cbt:"end_sequence_timestamp"`
 	Limit             int       `cbt:"limit"`
+	Cursor            string    `cbt:"cursor"` // start listing from a cursor saved from an earlier list
 }
 
 // ListFills returns a list of fills based on the parameters you include.
 func (c *Client) ListFills(params ListFillsParameters) (l FillList, err error) {
-	l.Pagination = Pagination{
-		client:     c,
-		parent:     &l,
-		parameters: params,
+	l.Pagination = Pagination[ListFillsParameters, Fill]{pager: c.fillsPager(params)}
+
+	err = l.NextPage()
+	return
+}
+
+// IterFills returns an iterator over every fill matching the parameters, fetching pages as needed.
+func (c *Client) IterFills(params ListFillsParameters) *Iterator[Fill] {
+	return newIterator(c.fillsPager(params))
+}
+
+// StreamFills sends every fill matching the parameters on a channel as the pages are fetched. See
+// Iterator.Stream.
+func (c *Client) StreamFills(ctx context.Context, params ListFillsParameters) (<-chan Fill, <-chan error) {
+	return c.IterFills(params).Stream(ctx)
+}
+
+// Fills returns every fill matching the parameters as a sequence for use with range, fetching pages as needed:
+//
+//	for f, err := range client.Fills(ctx, params) {
+//		...
+//	}
+func (c *Client) Fills(ctx context.Context, params ListFillsParameters) iter.Seq2[Fill, error] {
+	return c.IterFills(params).Seq(ctx)
+}
+
+func (c *Client) fillsPager(params ListFillsParameters) pager[ListFillsParameters, Fill] {
+	return pager[ListFillsParameters, Fill]{
+		client: c,
+		params: params,
 
 		method:   Get,
 		endpoint: listFillsEndpoint,
+		key:      "fills",
 	}
+}
 
-	err = l.NextPage()
+// allFills fetches every fill matching the parameters, sorted oldest first
+func (c *Client) allFills(ctx context.Context, params ListFillsParameters) (fills []Fill, err error) {
+	it := c.IterFills(params)
+	for it.next(ctx) {
+		fills = append(fills, it.Value())
+	}
+	if err = it.Err(); err != nil {
+		return
+	}
+
+	sortFills(fills)
 	return
 }
 
+// sortFills sorts fills oldest first, keeping fills made at the same time in their original order
+func sortFills(fills []Fill) {
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].TradeTime.Before(fills[j].TradeTime) })
+}
+
 // GetOrder takes the order id assigned by Coinbase and returns a populated `Order` object containing the
 // latest details from the server.
 func (c *Client) GetOrder(id string) (o Order, err error) {
-	// get order
-	var data []byte
-	if data, err = c.makeRequest(Get, fmt.Sprintf(getOrderEndpoint, id), url.Values{}, []byte{}, nil, nil); err != nil {
-		return
-	}
+	return c.getOrder(context.Background(), id)
+}
 
-	// unmarshal the response, but the order config won't match up
+// getOrder works like GetOrder, with ctx used for the request
+func (c *Client) getOrder(ctx context.Context, id string) (o Order, err error) {
 	wrapper := &struct {
 		Order *Order `json:"order"`
 	}{&o}
 
-	if err = json.Unmarshal(data, wrapper); err != nil {
-		return
-	}
-
-	// unmarshal just the order config
-	ocwrapper := &struct {
-		Order struct {
-			Config map[string]OrderConfiguration `json:"order_configuration"`
-		} `json:"order"`
-	}{}
-
-	if err = json.Unmarshal(data, ocwrapper); err != nil {
+	var data []byte
+	if data, err = c.makeRequest(ctx, Get, fmt.Sprintf(getOrderEndpoint, id), url.Values{}, []byte{}, wrapper, nil); err != nil {
 		return
 	}
-
-	for _, v := range ocwrapper.Order.Config {
-		o.OrderConfiguration = v
-		break
-	}
-	o.OrderConfiguration.Type = o.OrderConfiguration.getType()
-
+	o.Raw = c.rawItem(data, "order")
 	return
 }
 
@@ -429,7 +928,9 @@ func (c *Client) UpdateOrder(order *Order) (err error) {
 	return
 }
 
-// PlaceMarketIOC is a helper function to place a market "immediate or cancel" order.
+// PlaceMarketIOC is a helper function to place a market "immediate or cancel" order. The size of
+// a buy is in the quote currency (how much to spend), and the size of a sell is in the base
+// currency (how much to sell). Use PlaceMarketIOCBase to buy an exact amount of the base currency.
 func (c *Client) PlaceMarketIOC(clientOrderId string, productId string, side Side, size decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
 	oc := OrderConfiguration{
 		Type: MarketIOC,
@@ -442,6 +943,16 @@ func (c *Client) PlaceMarketIOC(clientOrderId string, productId string, side Sid
 	return c.CreateOrder(clientOrderId, productId, side, oc)
 }
 
+// PlaceMarketIOCBase is a helper function to place a market "immediate or cancel" order for an
+// exact amount of the base currency, whether buying or selling.
+func (c *Client) PlaceMarketIOCBase(clientOrderId string, productId string, side Side, baseSize decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	oc := OrderConfiguration{
+		Type:     MarketIOC,
+		BaseSize: baseSize,
+	}
+	return c.CreateOrder(clientOrderId, productId, side, oc)
+}
+
 // PlaceLimitGTC is a helper function to place a limit "good till closed" order. If you want to place
 // a "post only" order, set postOnly to true.
 func (c *Client) PlaceLimitGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, postOnly bool) (order Order, errorType CreateOrderError, err error) {
@@ -469,11 +980,35 @@ func (c *Client) PlaceLimitGTD(clientOrderId string, productId string, side Side
 	return c.CreateOrder(clientOrderId, productId, side, oc)
 }
 
+// PlaceLimitIOC is a helper function to place a limit "immediate or cancel" order: whatever can be
+// filled at the limit price or better is filled straight away, and the rest is cancelled.
+func (c *Client) PlaceLimitIOC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	oc := OrderConfiguration{
+		Type:       LimitIOC,
+		BaseSize:   size,
+		LimitPrice: price,
+	}
+
+	return c.CreateOrder(clientOrderId, productId, side, oc)
+}
+
+// PlaceLimitFOK is a helper function to place a limit "fill or kill" order: the order is either
+// filled completely at the limit price or better straight away, or cancelled.
+func (c *Client) PlaceLimitFOK(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	oc := OrderConfiguration{
+		Type:       LimitFOK,
+		BaseSize:   size,
+		LimitPrice: price,
+	}
+
+	return c.CreateOrder(clientOrderId, productId, side, oc)
+}
+
 // PlaceStopLimitGTC is a helper function to place a limit "good till close" order with a stop loss
 // price.
 func (c *Client) PlaceStopLimitGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopPrice decimal.Decimal, stopDirection StopDirection) (order Order, errorType CreateOrderError, err error) {
 	oc := OrderConfiguration{
-		Type:          LimitGTD,
+		Type:          StopLimitGTC,
 		BaseSize:      size,
 		LimitPrice:    price,
 		StopPrice:     stopPrice,
@@ -487,7 +1022,7 @@ func (c *Client) PlaceStopLimitGTC(clientOrderId string, productId string, side
 // price.
 func (c *Client) PlaceStopLimitGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopPrice decimal.Decimal, stopDirection StopDirection, endTime time.Time) (order Order, errorType CreateOrderError, err error) {
 	oc := OrderConfiguration{
-		Type:          LimitGTD,
+		Type:          StopLimitGTD,
 		BaseSize:      size,
 		LimitPrice:    price,
 		StopPrice:     stopPrice,
@@ -497,3 +1032,31 @@ func (c *Client) PlaceStopLimitGTD(clientOrderId string, productId string, side
 
 	return c.CreateOrder(clientOrderId, productId, side, oc)
 }
+
+// PlaceBracketGTC is a helper function to place a "good till cancelled" bracket order, which exits a
+// position either at the limit price (taking profit), or with a stop limit order that is placed
+// automatically if the price reaches stopTriggerPrice first (stopping losses).
+func (c *Client) PlaceBracketGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopTriggerPrice decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	oc := OrderConfiguration{
+		Type:             TriggerBracketGTC,
+		BaseSize:         size,
+		LimitPrice:       price,
+		StopTriggerPrice: stopTriggerPrice,
+	}
+
+	return c.CreateOrder(clientOrderId, productId, side, oc)
+}
+
+// PlaceBracketGTD is a helper function to place a limit "good till date" order with a stop
+// attached (see PlaceBracketGTC).
+func (c *Client) PlaceBracketGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopTriggerPrice decimal.Decimal, endTime time.Time) (order Order, errorType CreateOrderError, err error) {
+	oc := OrderConfiguration{
+		Type:             TriggerBracketGTD,
+		BaseSize:         size,
+		LimitPrice:       price,
+		StopTriggerPrice: stopTriggerPrice,
+		EndTime:          endTime,
+	}
+
+	return c.CreateOrder(clientOrderId, productId, side, oc)
+}
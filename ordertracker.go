@@ -0,0 +1,197 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderEventType is a step in an order's life.
+type OrderEventType string
+
+const (
+	OrderAccepted        OrderEventType = "ACCEPTED"
+	OrderPartiallyFilled OrderEventType = "PARTIALLY_FILLED"
+	OrderFilled          OrderEventType = "FILLED"
+	OrderCancelled       OrderEventType = "CANCELLED"
+	OrderExpired         OrderEventType = "EXPIRED"
+	OrderFailed          OrderEventType = "FAILED"
+)
+
+// OrderEvent reports a change to a tracked order, along with its latest state. Orders from the user
+// channel only have the fields included in the websocket feed populated.
+type OrderEvent struct {
+	Type  OrderEventType
+	Order Order
+}
+
+// OrderTracker watches a set of orders and calls OnEvent as each one is accepted, partly filled,
+// and finally filled, cancelled, expired, or failed. Updates are taken from user channel messages
+// passed to Apply; while Run is going, any order that hasn't had a websocket update for Interval is
+// fetched with GetOrder instead, so the tracker works with or without a websocket. Orders stop
+// being tracked once they are finished. It is safe to use from multiple goroutines.
+type OrderTracker struct {
+	Interval time.Duration    // how long to wait for a websocket update before polling (default 5 seconds)
+	OnEvent  func(OrderEvent) // called for each event, in the goroutine that received the update
+	OnError  func(error)      // called when polling an order fails during Run
+
+	client *Client
+	mu     sync.Mutex
+	orders map[string]*trackedOrder
+}
+
+type trackedOrder struct {
+	accepted bool
+	filled   decimal.Decimal
+	updated  time.Time // when the order was last seen, by either means
+}
+
+// NewOrderTracker creates an OrderTracker that uses this client to poll orders.
+func (c *Client) NewOrderTracker(onEvent func(OrderEvent)) *OrderTracker {
+	return &OrderTracker{
+		Interval: time.Second * 5,
+		OnEvent:  onEvent,
+		client:   c,
+		orders:   make(map[string]*trackedOrder),
+	}
+}
+
+// Track starts watching the given orders.
+func (t *OrderTracker) Track(orderIDs ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range orderIDs {
+		if _, ok := t.orders[id]; !ok {
+			t.orders[id] = &trackedOrder{}
+		}
+	}
+}
+
+// Untrack stops watching an order.
+func (t *OrderTracker) Untrack(orderID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.orders, orderID)
+}
+
+// Tracking returns the ids of the orders still being watched.
+func (t *OrderTracker) Tracking() (orderIDs []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id := range t.orders {
+		orderIDs = append(orderIDs, id)
+	}
+	return
+}
+
+// Apply takes the updates to tracked orders from a user channel message. Messages from other
+// channels, and updates to orders that aren't tracked, are ignored.
+func (t *OrderTracker) Apply(msg WebsocketMessage) (err error) {
+	if msg.Channel != UserChannel {
+		return
+	}
+
+	var events []UserEvent
+	if events, err = msg.UserEvents(); err != nil {
+		return
+	}
+	for _, e := range events {
+		for _, o := range e.Orders {
+			t.Update(o.Order)
+		}
+	}
+	return
+}
+
+// Update compares the latest state of an order with what was seen before, and reports any events.
+// It is called by Apply and Run, but can also be used with orders fetched some other way.
+func (t *OrderTracker) Update(o Order) {
+	var events []OrderEvent
+
+	t.mu.Lock()
+	s, ok := t.orders[o.ID]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	s.updated = time.Now()
+
+	status := o.Status
+	if !s.accepted && status != Pending && status != Failed && status != UnknownStatus && status != "" {
+		s.accepted = true
+		events = append(events, OrderEvent{OrderAccepted, o})
+	}
+	if o.FilledSize.GreaterThan(s.filled) {
+		s.filled = o.FilledSize
+		if status != Filled {
+			events = append(events, OrderEvent{OrderPartiallyFilled, o})
+		}
+	}
+
+	var final OrderEventType
+	switch status {
+	case Filled:
+		final = OrderFilled
+	case Cancelled:
+		final = OrderCancelled
+	case Expired:
+		final = OrderExpired
+	case Failed:
+		final = OrderFailed
+	}
+	if final != "" {
+		events = append(events, OrderEvent{final, o})
+		delete(t.orders, o.ID)
+	}
+	t.mu.Unlock()
+
+	if t.OnEvent != nil {
+		for _, e := range events {
+			t.OnEvent(e)
+		}
+	}
+}
+
+// Run polls tracked orders that haven't been updated by the websocket recently, until the context
+// is done.
+func (t *OrderTracker) Run(ctx context.Context) error {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = time.Second * 5
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		var stale []string
+		t.mu.Lock()
+		for id, s := range t.orders {
+			if time.Since(s.updated) >= interval {
+				stale = append(stale, id)
+			}
+		}
+		t.mu.Unlock()
+
+		for _, id := range stale {
+			o, err := t.client.getOrder(ctx, id)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if t.OnError != nil {
+					t.OnError(err)
+				}
+				continue
+			}
+			t.Update(o)
+		}
+	}
+}
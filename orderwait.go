@@ -0,0 +1,120 @@
+package coinbasetrade
+
+import (
+	"context"
+	"time"
+)
+
+// PollPolicy controls how often helpers like WaitForFill check on an order. The first check is
+// made straight away, then the wait between checks starts at MinInterval and grows by Multiplier
+// each time, up to MaxInterval.
+type PollPolicy struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	Multiplier  float64 // 1 or less keeps the interval at MinInterval
+}
+
+// DefaultPollPolicy is used by clients that aren't given a policy of their own.
+var DefaultPollPolicy = PollPolicy{
+	MinInterval: time.Millisecond * 500,
+	MaxInterval: time.Second * 5,
+	Multiplier:  1.5,
+}
+
+// next returns the interval to wait after the given one (zero before the first wait)
+func (p PollPolicy) next(prev time.Duration) time.Duration {
+	if prev <= 0 {
+		return p.MinInterval
+	}
+	next := prev
+	if p.Multiplier > 1 {
+		next = time.Duration(float64(prev) * p.Multiplier)
+	}
+	if p.MaxInterval > 0 && next > p.MaxInterval {
+		next = p.MaxInterval
+	}
+	return next
+}
+
+// sleep waits for d, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Terminal returns true if an order with this status will never change again.
+func (s OrderStatus) Terminal() bool {
+	switch s {
+	case Filled, Cancelled, Expired, Failed:
+		return true
+	}
+	return false
+}
+
+// WaitForFill polls GetOrder, using the client's PollPolicy, until the order is filled,
+// cancelled, expired, or failed, and returns its final state. Check the order's Status to see
+// which; an error is only returned if the order couldn't be fetched or the context is done.
+func (c *Client) WaitForFill(ctx context.Context, orderID string) (o Order, err error) {
+	o.ID = orderID
+	err = o.Wait(ctx, c)
+	return
+}
+
+// Wait polls for updates to the order, using the client's PollPolicy, until it is filled,
+// cancelled, expired, or failed. The order is updated in place each time it is fetched.
+func (o *Order) Wait(ctx context.Context, c *Client) (err error) {
+	var interval time.Duration
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var latest Order
+		if latest, err = c.getOrder(ctx, o.ID); err != nil {
+			return
+		}
+		*o = latest
+		if o.Status.Terminal() {
+			return
+		}
+
+		interval = c.Poll.next(interval)
+		if err = sleep(ctx, interval); err != nil {
+			return
+		}
+	}
+}
+
+// CancelAndConfirm cancels an order and waits until it is actually finished, since it may still
+// be filled before the cancellation takes effect. It returns the order's final state (which is
+// Filled rather than Cancelled if the fill won), and any fills the order had, oldest first. If
+// the cancellation is rejected because the order had already finished, the order is returned
+// without an error; otherwise the rejection is returned as a *CancelOrdersFailure.
+func (c *Client) CancelAndConfirm(ctx context.Context, orderID string) (o Order, fills []Fill, err error) {
+	if _, err = c.cancelOrders(ctx, []string{orderID}); err != nil {
+		cancelErr := err
+		if o, err = c.getOrder(ctx, orderID); err != nil {
+			return
+		}
+		if !o.Status.Terminal() {
+			err = cancelErr
+			return
+		}
+	} else {
+		o.ID = orderID
+		if err = o.Wait(ctx, c); err != nil {
+			return
+		}
+	}
+
+	if o.FilledSize.IsPositive() {
+		fills, err = c.allFills(ctx, ListFillsParameters{OrderID: orderID})
+	}
+	return
+}
@@ -0,0 +1,20 @@
+module github.com/jmacwhyte/go-coinbase-trade/oteltracing
+
+go 1.23
+
+require (
+	github.com/jmacwhyte/go-coinbase-trade v0.0.0-20261015210142-e2e6601017d7
+	go.opentelemetry.io/otel v1.34.0
+	go.opentelemetry.io/otel/trace v1.34.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/shopspring/decimal v1.3.1 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.34.0 // indirect
+)
+
+replace github.com/jmacwhyte/go-coinbase-trade => ../
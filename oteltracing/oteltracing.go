@@ -0,0 +1,78 @@
+// Package oteltracing creates OpenTelemetry spans for the requests and websocket messages of a
+// coinbasetrade.Client. It is a module of its own, so only programs that use it depend on
+// OpenTelemetry.
+//
+//	client := coinbasetrade.NewClient(&coinbasetrade.ClientConfig{Tracer: oteltracing.New(nil), ...})
+//
+// Requests are made with the context of their span, so a Transport instrumented with otelhttp
+// adds its spans beneath it.
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans.
+const ScopeName = "github.com/jmacwhyte/go-coinbase-trade"
+
+// Tracer implements coinbasetrade.Tracer with an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ coinbasetrade.Tracer = (*Tracer)(nil)
+
+// New creates a Tracer using the given provider, or the global one if nil.
+func New(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: provider.Tracer(ScopeName)}
+}
+
+// StartRequest starts a client span named after the method and endpoint, i.e.
+// "coinbase GET /orders/historical/{id}". The status code and number of attempts are recorded
+// when it ends, and the span is marked as failed if there was an error.
+func (t *Tracer) StartRequest(ctx context.Context, method coinbasetrade.Method, endpoint string) (context.Context, func(status, attempts int, err error)) {
+	ctx, span := t.tracer.Start(ctx, fmt.Sprintf("coinbase %s %s", method, endpoint),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", string(method)),
+			attribute.String("coinbase.endpoint", endpoint),
+		),
+	)
+
+	return ctx, func(status, attempts int, err error) {
+		if status != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+		}
+		span.SetAttributes(attribute.Int("coinbase.attempts", attempts))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// StartWebsocketMessage starts a consumer span for a websocket message, named after its channel.
+// It lasts until the message has been read from Messages, so it shows how long messages wait for
+// the reader.
+func (t *Tracer) StartWebsocketMessage(msg coinbasetrade.WebsocketMessage) func() {
+	_, span := t.tracer.Start(context.Background(), fmt.Sprintf("coinbase websocket %s", msg.Channel),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("coinbase.channel", string(msg.Channel)),
+			attribute.Int64("coinbase.sequence_num", msg.SequenceNum),
+			attribute.Int("coinbase.events_size", len(msg.Events)),
+		),
+	)
+	return func() { span.End() }
+}
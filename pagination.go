@@ -1,71 +1,333 @@
 package coinbasetrade
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
+	"iter"
 	"strconv"
 )
 
-// Pagination values need to be extracted from some API replies, but we would like to keep these
-// values from being exposed outside this library. This struct is used for umarshaling pagination
-// data from API responses, and then each request struct saves this information internally, in
-// unexported fields.
-type Pagination struct {
-	parent     interface{}
-	parameters interface{}
-	method     Method
-	endpoint   string
-
-	client *Client
+// pageState tracks where a paged list is up to.
+type pageState struct {
 	noNext bool
-	end    bool
 	// pagination with cursor
 	cursor string
 
-	// pagination without cursor (limit must be non-zero)
-	limit  int
-	offset int
+	// pagination without cursor (products only; limit must be non-zero)
+	offsetPaging bool
+	limit        int
+	offset       int
 }
 
-func (p *Pagination) Next() bool {
-	return !p.end
-}
+// pager walks through the pages of a list endpoint, sending params with each request and decoding
+// the items found under key in each response. It is shared by the list objects returned by the
+// List functions, and by the typed Iterators.
+type pager[P, T any] struct {
+	client   *Client
+	params   P
+	method   Method
+	endpoint string
+	key      string // the field of the response that holds the items
 
-func (p *Pagination) NextPage() error {
-	if p.noNext {
-		p.end = true
-		return nil
-	}
+	pageState
+}
 
+// fetch requests the next page and returns its items
+func (p *pager[P, T]) fetch(ctx context.Context) (items []T, err error) {
 	pg := struct {
 		HasNext     bool   `json:"has_next"`
 		Cursor      string `json:"cursor"`
 		NumProducts int    `json:"num_products"` // only used by offset pagination
 	}{}
 
-	query, err := parametersToValues(p.parameters)
+	query, err := parametersToValues(p.params)
 	if err != nil {
-		return err
+		return
 	}
 
 	if p.cursor != "" {
-		query.Add("cursor", p.cursor)
+		query.Set("cursor", p.cursor)
 	} else if p.offset > 0 { // only used by offset pagination
 		query.Add("offset", strconv.Itoa(p.offset))
 	}
 
-	if _, err := p.client.makeRequest(p.method, p.endpoint, query, []byte{}, p.parent, &pg); err != nil {
-		return err
+	var data []byte
+	if data, err = p.client.makeRequest(ctx, p.method, p.endpoint, query, []byte{}, nil, &pg); err != nil {
+		return
 	}
 
-	p.noNext, p.cursor = !pg.HasNext, pg.Cursor
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err == nil && len(raw[p.key]) > 0 {
+		err = json.Unmarshal(raw[p.key], &items)
+	}
+	if err != nil {
+		return nil, formatError("unmarshal api result", err)
+	}
+	keepRaw(p.client, data, p.key, items)
 
-	// if using offset pagination
-	if p.cursor == "" {
-		if p.limit == 0 {
-			return errors.New("no limit specified for offset pagination")
-		}
+	if p.offsetPaging {
 		p.offset += p.limit
 		p.noNext = p.offset >= pg.NumProducts
+	} else {
+		p.noNext, p.cursor = !pg.HasNext, pg.Cursor
+	}
+	return
+}
+
+// Pagination values need to be extracted from some API replies, but we would like to keep these
+// values from being exposed outside this library. Each list embeds a Pagination for its parameters
+// (P) and items (T), which keeps this information internally, in unexported fields.
+type Pagination[P, T any] struct {
+	// Accumulate makes NextPage append each page to the items already in the list, rather than
+	// replacing them.
+	Accumulate bool `json:"-"`
+
+	end bool
+	pager[P, T]
+}
+
+// Cursor returns the cursor for the page after the current one, or an empty string if there are
+// no more pages. Save it once the current page has been processed, and pass it in the Cursor
+// parameter of a new list to carry on from there. Lists that page by offset (products) have no
+// cursor; use Offset instead.
+func (p *Pagination[P, T]) Cursor() string {
+	if p.noNext {
+		return ""
+	}
+	return p.cursor
+}
+
+// Offset returns the offset of the page after the current one, for lists that page by offset
+// (products). Pass it in the Offset parameter of a new list to carry on from there.
+func (p *Pagination[P, T]) Offset() int {
+	return p.offset
+}
+
+func (p *Pagination[P, T]) Next() bool {
+	return !p.end
+}
+
+// nextPage fetches the next page and returns its items. items points to the list's item slice,
+// which is replaced by the page, or has the page appended to it if Accumulate is set. Each list
+// type has its own NextPage that passes its items in, so the page always goes to the caller's copy
+// of the list.
+func (p *Pagination[P, T]) nextPage(ctx context.Context, items *[]T) (page []T, err error) {
+	if p.noNext {
+		p.end = true
+		return
+	}
+
+	if page, err = p.fetch(ctx); err != nil {
+		return
+	}
+
+	if p.Accumulate {
+		*items = append(*items, page...)
+	} else {
+		*items = page
+	}
+	return
+}
+
+// AllLimits caps how much is fetched by All. Zero means no limit.
+type AllLimits struct {
+	MaxPages int
+	MaxItems int
+}
+
+// all gathers the items of a list from the ones it holds now onwards
+func (p *Pagination[P, T]) all(ctx context.Context, limits AllLimits, items *[]T) (all []T, err error) {
+	page := *items
+	for pages := 1; p.Next(); pages++ {
+		all = append(all, page...)
+		if limits.MaxItems > 0 && len(all) >= limits.MaxItems {
+			return all[:limits.MaxItems], nil
+		}
+		if limits.MaxPages > 0 && pages >= limits.MaxPages {
+			return
+		}
+
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if page, err = p.nextPage(ctx, items); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Page is one page of results from a list endpoint.
+type Page[T any] struct {
+	Items   []T
+	Cursor  string // the cursor for the next page, if the list is paged by cursor
+	Offset  int    // the offset of the next page, if the list is paged by offset
+	HasNext bool
+}
+
+// Iterator goes through the results of a list endpoint one at a time, fetching each page as it is
+// needed:
+//
+//	it := client.IterOrders(params)
+//	for it.Next() {
+//		order := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator[T any] struct {
+	fetch func(context.Context) ([]T, error)
+	*pageState
+
+	started bool
+	items   []T
+	i       int
+	err     error
+}
+
+// newIterator creates an iterator for the items of a list endpoint
+func newIterator[P, T any](p pager[P, T]) *Iterator[T] {
+	return &Iterator[T]{fetch: p.fetch, pageState: &p.pageState, i: -1}
+}
+
+// NextPage fetches the next page of results. Once there are no more pages, it returns an empty
+// page. If it is mixed with Next, any items left in the current page are skipped.
+func (it *Iterator[T]) NextPage() (page Page[T], err error) {
+	return it.nextPage(context.Background())
+}
+
+// nextPage works like NextPage, with ctx used for the request
+func (it *Iterator[T]) nextPage(ctx context.Context) (page Page[T], err error) {
+	if it.err != nil {
+		return page, it.err
+	}
+	if it.started && it.noNext {
+		return
+	}
+	it.started = true
+
+	if page.Items, err = it.fetch(ctx); err != nil {
+		it.err = err
+		return
+	}
+
+	page.HasNext = !it.noNext
+	if page.HasNext {
+		page.Cursor, page.Offset = it.cursor, it.offset
+	}
+	it.items, it.i = page.Items, -1
+	return
+}
+
+// Next moves to the next item, fetching another page if needed, and returns false when there are
+// no more items or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	return it.next(context.Background())
+}
+
+// next works like Next, with ctx used for any request
+func (it *Iterator[T]) next(ctx context.Context) bool {
+	for it.i+1 >= len(it.items) {
+		if it.err != nil || (it.started && it.noNext) {
+			return false
+		}
+		if _, err := it.nextPage(ctx); err != nil {
+			return false
+		}
+	}
+	it.i++
+	return true
+}
+
+// Value returns the current item.
+func (it *Iterator[T]) Value() (v T) {
+	if it.i >= 0 && it.i < len(it.items) {
+		v = it.items[it.i]
+	}
+	return
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All fetches the remaining pages and returns their items, up to the given limits.
+func (it *Iterator[T]) All(ctx context.Context, limits AllLimits) (all []T, err error) {
+	for pages := 1; ; pages++ {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		var page Page[T]
+		if page, err = it.nextPage(ctx); err != nil {
+			return
+		}
+		all = append(all, page.Items...)
+		if limits.MaxItems > 0 && len(all) >= limits.MaxItems {
+			return all[:limits.MaxItems], nil
+		}
+		if !page.HasNext || (limits.MaxPages > 0 && pages >= limits.MaxPages) {
+			return
+		}
+	}
+}
+
+// Stream sends the remaining items on a channel as each page is fetched, so only one page is held
+// in memory at a time. Both channels are closed when the items run out, ctx is cancelled, or an
+// error occurs; the error (if any) is sent on the error channel first. The items channel must be
+// drained, or ctx cancelled, for the fetching goroutine to finish.
+func (it *Iterator[T]) Stream(ctx context.Context) (<-chan T, <-chan error) {
+	items, errs := make(chan T), make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(items)
+
+		for it.next(ctx) {
+			select {
+			case items <- it.Value():
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			errs <- err
+		}
+	}()
+
+	return items, errs
+}
+
+// Seq returns the remaining items as a range-over-func sequence, fetching pages as needed. If
+// fetching fails or ctx is cancelled, the error is yielded with a zero item and the sequence ends.
+func (it *Iterator[T]) Seq(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			if err := ctx.Err(); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !it.next(ctx) {
+				break
+			}
+			if !yield(it.Value(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// Cursor returns the cursor for the page after the current one, as for Pagination.Cursor.
+func (it *Iterator[T]) Cursor() string {
+	if it.noNext {
+		return ""
 	}
-	return nil
+	return it.cursor
 }
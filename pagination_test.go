@@ -0,0 +1,335 @@
+package coinbasetrade_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+)
+
+// pagingServer serves orders and fills paged by cursor, and products paged by offset, as Coinbase
+// does. It counts the list requests it receives.
+type pagingServer struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newPagingServer(t *testing.T, n int) *pagingServer {
+	var orders []coinbasetrade.Order
+	var fills []coinbasetrade.Fill
+	var products []coinbasetrade.Product
+	for i := 1; i <= n; i++ {
+		orders = append(orders, coinbasetrade.Order{ID: fmt.Sprintf("order-%d", i)})
+		fills = append(fills, coinbasetrade.Fill{ID: fmt.Sprintf("fill-%d", i)})
+		products = append(products, coinbasetrade.Product{ID: fmt.Sprintf("product-%d", i)})
+	}
+
+	s := &pagingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		var res interface{}
+		switch path := strings.TrimPrefix(r.URL.Path, "/api/v3/brokerage"); path {
+		case "/orders/historical/batch":
+			res = cursorPage("orders", orders, query.Get("cursor"), limit)
+		case "/orders/historical/fills":
+			res = cursorPage("fills", fills, query.Get("cursor"), limit)
+		case "/products":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			end := min(offset+limit, len(products))
+			res = map[string]interface{}{"products": products[offset:end], "num_products": len(products)}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// cursorPage returns the page of items starting at the index in cursor
+func cursorPage[T any](key string, items []T, cursor string, limit int) map[string]interface{} {
+	start, _ := strconv.Atoi(cursor)
+	end := min(start+limit, len(items))
+	res := map[string]interface{}{key: items[start:end], "has_next": end < len(items)}
+	if end < len(items) {
+		res["cursor"] = strconv.Itoa(end)
+	}
+	return res
+}
+
+func (s *pagingServer) client() *coinbasetrade.Client {
+	return coinbasetrade.NewClient(&coinbasetrade.ClientConfig{
+		Host:   s.URL,
+		Path:   "/api/v3/brokerage",
+		Key:    "key",
+		Secret: "secret",
+		Retry:  &coinbasetrade.RetryPolicy{MaxAttempts: 1},
+	})
+}
+
+func orderIDs(orders []coinbasetrade.Order) (ids []string) {
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	return
+}
+
+func fillIDs(fills []coinbasetrade.Fill) (ids []string) {
+	for _, f := range fills {
+		ids = append(ids, f.ID)
+	}
+	return
+}
+
+func productIDs(products []coinbasetrade.Product) (ids []string) {
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	return
+}
+
+// pages describes the pages of 5 items fetched 2 at a time, and the items a list holds after each
+// page with and without Accumulate
+var pages = []struct {
+	page, replaced, accumulated []string
+}{
+	{[]string{"1", "2"}, []string{"1", "2"}, []string{"1", "2"}},
+	{[]string{"3", "4"}, []string{"3", "4"}, []string{"1", "2", "3", "4"}},
+	{[]string{"5"}, []string{"5"}, []string{"1", "2", "3", "4", "5"}},
+}
+
+func withPrefix(prefix string, ids []string) (out []string) {
+	for _, id := range ids {
+		out = append(out, prefix+id)
+	}
+	return
+}
+
+// checkPages pages through a list, checking the items returned by each fetch and held by the list
+// afterwards
+func checkPages(t *testing.T, prefix string, accumulate bool, first []string, held func() []string, next func() bool, fetch func() ([]string, error), cursor func() string) {
+	t.Helper()
+
+	if want := withPrefix(prefix, pages[0].page); !reflect.DeepEqual(first, want) {
+		t.Fatalf("first page = %v, want %v", first, want)
+	}
+	for n, p := range pages {
+		if n > 0 {
+			page, err := fetch()
+			if err != nil {
+				t.Fatalf("page %d: %s", n+1, err)
+			}
+			if want := withPrefix(prefix, p.page); !reflect.DeepEqual(page, want) {
+				t.Errorf("page %d = %v, want %v", n+1, page, want)
+			}
+		}
+
+		want := p.replaced
+		if accumulate {
+			want = p.accumulated
+		}
+		if got := held(); !reflect.DeepEqual(got, withPrefix(prefix, want)) {
+			t.Errorf("after page %d, list holds %v, want %v", n+1, got, withPrefix(prefix, want))
+		}
+		if cursor != nil {
+			wantCursor := strconv.Itoa(2 * (n + 1))
+			if n == len(pages)-1 {
+				wantCursor = ""
+			}
+			if got := cursor(); got != wantCursor {
+				t.Errorf("after page %d, cursor = %q, want %q", n+1, got, wantCursor)
+			}
+		}
+		if !next() {
+			t.Fatalf("after page %d, Next is false", n+1)
+		}
+	}
+
+	// the next fetch finds there are no more pages, and leaves the list as it is
+	page, err := fetch()
+	if err != nil || len(page) > 0 {
+		t.Errorf("fetch after the last page = %v, %v; want no items", page, err)
+	}
+	if next() {
+		t.Error("Next is true after the last page")
+	}
+}
+
+func TestCursorPaging(t *testing.T) {
+	for _, accumulate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("orders/accumulate=%t", accumulate), func(t *testing.T) {
+			srv := newPagingServer(t, 5)
+			l, err := srv.client().ListOrders(coinbasetrade.ListOrdersParameters{Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Accumulate = accumulate
+
+			checkPages(t, "order-", accumulate, orderIDs(l.Orders),
+				func() []string { return orderIDs(l.Orders) },
+				l.Next,
+				func() ([]string, error) {
+					orders, err := l.FetchPage()
+					return orderIDs(orders), err
+				},
+				l.Cursor)
+		})
+
+		t.Run(fmt.Sprintf("fills/accumulate=%t", accumulate), func(t *testing.T) {
+			srv := newPagingServer(t, 5)
+			l, err := srv.client().ListFills(coinbasetrade.ListFillsParameters{Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Accumulate = accumulate
+
+			checkPages(t, "fill-", accumulate, fillIDs(l.Fills),
+				func() []string { return fillIDs(l.Fills) },
+				l.Next,
+				func() ([]string, error) {
+					fills, err := l.FetchPage()
+					return fillIDs(fills), err
+				},
+				l.Cursor)
+		})
+	}
+}
+
+func TestOffsetPaging(t *testing.T) {
+	for _, accumulate := range []bool{false, true} {
+		t.Run(fmt.Sprintf("accumulate=%t", accumulate), func(t *testing.T) {
+			srv := newPagingServer(t, 5)
+			l, err := srv.client().ListProducts(coinbasetrade.ListProductsParameters{Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.Accumulate = accumulate
+
+			checkPages(t, "product-", accumulate, productIDs(l.Products),
+				func() []string { return productIDs(l.Products) },
+				l.Next,
+				func() ([]string, error) {
+					products, err := l.FetchPage()
+					return productIDs(products), err
+				},
+				nil)
+			if l.Offset() != 6 {
+				t.Errorf("Offset = %d, want 6", l.Offset())
+			}
+		})
+	}
+}
+
+func TestOffsetPagingResume(t *testing.T) {
+	srv := newPagingServer(t, 5)
+	l, err := srv.client().ListProducts(coinbasetrade.ListProductsParameters{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := productIDs(l.Products), []string{"product-3", "product-4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("products = %v, want %v", got, want)
+	}
+	if l.Offset() != 4 {
+		t.Errorf("Offset = %d, want 4", l.Offset())
+	}
+}
+
+func TestAllLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   coinbasetrade.AllLimits
+		want     int
+		requests int32
+	}{
+		{"no limit", coinbasetrade.AllLimits{}, 5, 3},
+		{"max pages", coinbasetrade.AllLimits{MaxPages: 2}, 4, 2},
+		{"max items", coinbasetrade.AllLimits{MaxItems: 3}, 3, 2},
+		{"max items on a page boundary", coinbasetrade.AllLimits{MaxItems: 2}, 2, 1},
+		{"max items above the total", coinbasetrade.AllLimits{MaxItems: 10}, 5, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run("orders/"+tt.name, func(t *testing.T) {
+			srv := newPagingServer(t, 5)
+			l, err := srv.client().ListOrders(coinbasetrade.ListOrdersParameters{Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			orders, err := l.All(context.Background(), tt.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := orderIDs(orders), withPrefix("order-", []string{"1", "2", "3", "4", "5"}[:tt.want]); !reflect.DeepEqual(got, want) {
+				t.Errorf("All = %v, want %v", got, want)
+			}
+			if got := srv.requests.Load(); got != tt.requests {
+				t.Errorf("made %d requests, want %d", got, tt.requests)
+			}
+		})
+
+		t.Run("products/"+tt.name, func(t *testing.T) {
+			srv := newPagingServer(t, 5)
+			l, err := srv.client().ListProducts(coinbasetrade.ListProductsParameters{Limit: 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			products, err := l.All(context.Background(), tt.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(products) != tt.want {
+				t.Errorf("All returned %d products, want %d", len(products), tt.want)
+			}
+			if got := srv.requests.Load(); got != tt.requests {
+				t.Errorf("made %d requests, want %d", got, tt.requests)
+			}
+		})
+
+		t.Run("iterator/"+tt.name, func(t *testing.T) {
+			srv := newPagingServer(t, 5)
+			fills, err := srv.client().IterFills(coinbasetrade.ListFillsParameters{Limit: 2}).All(context.Background(), tt.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fills) != tt.want {
+				t.Errorf("All returned %d fills, want %d", len(fills), tt.want)
+			}
+			if got := srv.requests.Load(); got != tt.requests {
+				t.Errorf("made %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestAllCancelled(t *testing.T) {
+	srv := newPagingServer(t, 5)
+	l, err := srv.client().ListOrders(coinbasetrade.ListOrdersParameters{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.All(ctx, coinbasetrade.AllLimits{}); err == nil {
+		t.Error("All succeeded with a cancelled context")
+	}
+	if _, err := srv.client().IterOrders(coinbasetrade.ListOrdersParameters{Limit: 2}).All(ctx, coinbasetrade.AllLimits{}); err == nil {
+		t.Error("Iterator.All succeeded with a cancelled context")
+	}
+}
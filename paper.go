@@ -0,0 +1,716 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BookSource provides the order books that a PaperClient trades against. *Client and
+// *PublicClient both satisfy it.
+type BookSource interface {
+	GetProductBook(productID string, limit int, aggregationIncrement decimal.Decimal) (ProductBook, error)
+}
+
+// Default fee rates for a PaperClient, from the lowest tier of Coinbase's fee schedule.
+var (
+	DefaultPaperMakerFeeRate = decimal.RequireFromString("0.004")
+	DefaultPaperTakerFeeRate = decimal.RequireFromString("0.006")
+)
+
+// PaperClient simulates placing orders against live order books, without touching real funds. It
+// has the same order methods as Client, and keeps virtual balances and fills. Orders are matched
+// like this:
+//
+//   - Market orders, and the part of a limit order that crosses the book when it is placed (or
+//     when its stop is triggered), are filled against the levels of the book as a taker. The book
+//     isn't changed, so later orders can be filled against the same levels. A market order that
+//     uses up the book is cancelled with the part that was filled, like an IOC order.
+//   - The rest of a GTC or GTD limit order waits, and is filled at its limit price as a maker once
+//     the best price on the other side of the book reaches it, up to the size on the other side
+//     at or better than that price. Waiting orders are checked by Update, or regularly while Run
+//     is going.
+//   - A stop limit order is triggered once the mid price reaches its stop price.
+//   - Bracket orders aren't supported, and OrderOptions are ignored.
+//
+// Funds for waiting orders are held, so an order that needs more than the available balance is
+// rejected with InsufficientFund.
+type PaperClient struct {
+	MakerFeeRate decimal.Decimal
+	TakerFeeRate decimal.Decimal
+	BookDepth    int           // how many levels of the book are fetched to match against (default 100)
+	Interval     time.Duration // how often Run checks waiting orders (default 5 seconds)
+	OnFill       func(Fill)    // called for every simulated fill
+	OnError      func(error)   // called when fetching a book fails during Run
+
+	market   BookSource
+	mu       sync.Mutex
+	balances map[string]decimal.Decimal
+	orders   []*Order          // in the order they were placed
+	byID     map[string]*Order // keyed by order id
+	byClient map[string]*Order // keyed by client order id
+	fills    []Fill
+}
+
+// NewPaperClient creates a paper trading client that matches orders against books from market,
+// starting with the given balances (keyed by currency, e.g. "USD").
+func NewPaperClient(market BookSource, balances map[string]decimal.Decimal) *PaperClient {
+	p := &PaperClient{
+		MakerFeeRate: DefaultPaperMakerFeeRate,
+		TakerFeeRate: DefaultPaperTakerFeeRate,
+		BookDepth:    100,
+		Interval:     time.Second * 5,
+
+		market:   market,
+		balances: make(map[string]decimal.Decimal),
+		byID:     make(map[string]*Order),
+		byClient: make(map[string]*Order),
+	}
+	for k, v := range balances {
+		p.balances[k] = v
+	}
+	return p
+}
+
+// Balances returns the total balance of each currency, including funds held for open orders.
+func (p *PaperClient) Balances() map[string]decimal.Decimal {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	balances := make(map[string]decimal.Decimal, len(p.balances))
+	for k, v := range p.balances {
+		balances[k] = v
+	}
+	return balances
+}
+
+// Available returns the balance of a currency that isn't held for open orders.
+func (p *PaperClient) Available(currency string) decimal.Decimal {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.available(currency)
+}
+
+// OrderHistory returns every order that has been placed, oldest first.
+func (p *PaperClient) OrderHistory() []Order {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	orders := make([]Order, len(p.orders))
+	for i, o := range p.orders {
+		orders[i] = *o
+	}
+	return orders
+}
+
+// OpenOrders returns the orders that are waiting to be filled, oldest first.
+func (p *PaperClient) OpenOrders() (orders []Order) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, o := range p.orders {
+		if o.Status == Open {
+			orders = append(orders, *o)
+		}
+	}
+	return
+}
+
+// FillHistory returns every simulated fill, oldest first.
+func (p *PaperClient) FillHistory() []Fill {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fills := make([]Fill, len(p.fills))
+	copy(fills, p.fills)
+	return fills
+}
+
+// CreateOrder simulates placing an order. See Client.CreateOrder.
+func (p *PaperClient) CreateOrder(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrderWithOptions(clientOrderId, productId, side, orderConfig, OrderOptions{})
+}
+
+// CreateOrderWithOptions simulates placing an order. The options are ignored.
+func (p *PaperClient) CreateOrderWithOptions(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration, opts OrderOptions) (order Order, errorType CreateOrderError, err error) {
+	if clientOrderId == "" {
+		clientOrderId = NewUUID()
+	}
+	if orderConfig.Type == "" {
+		orderConfig.Type = orderConfig.getType()
+	}
+	if err = orderConfig.validate(); err != nil {
+		err = formatError("create order", err)
+		return
+	}
+
+	var book ProductBook
+	if book, err = p.book(productId); err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	// as on Coinbase, an order with a client order id that has been used already isn't placed
+	if o, ok := p.byClient[clientOrderId]; ok {
+		order = *o
+		p.mu.Unlock()
+		return
+	}
+
+	o := &Order{
+		ID:                 NewUUID(),
+		Product:            productId,
+		ClientOrderID:      clientOrderId,
+		Side:               side,
+		OrderConfiguration: orderConfig,
+		CreatedTime:        time.Now(),
+		ProductType:        ProductTypeSpot,
+	}
+	var fills []Fill
+	fills, errorType = p.place(o, book)
+	order = *o
+	p.mu.Unlock()
+
+	p.notify(fills)
+	if errorType != "" {
+		order = Order{}
+		err = &CreateOrderFailure{Reason: errorType}
+	}
+	return
+}
+
+// PlaceOrder works like CreateOrderWithOptions, but returns a single error. See Client.PlaceOrder.
+func (p *PaperClient) PlaceOrder(clientOrderId string, productId string, side Side, orderConfig OrderConfiguration, opts OrderOptions) (order Order, err error) {
+	order, _, err = p.CreateOrderWithOptions(clientOrderId, productId, side, orderConfig, opts)
+	return
+}
+
+// PlaceMarketIOC simulates a market order. See Client.PlaceMarketIOC.
+func (p *PaperClient) PlaceMarketIOC(clientOrderId string, productId string, side Side, size decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	oc := OrderConfiguration{Type: MarketIOC}
+	if side == Buy {
+		oc.QuoteSize = size
+	} else {
+		oc.BaseSize = size
+	}
+	return p.CreateOrder(clientOrderId, productId, side, oc)
+}
+
+// PlaceMarketIOCBase simulates a market order sized in the base currency. See
+// Client.PlaceMarketIOCBase.
+func (p *PaperClient) PlaceMarketIOCBase(clientOrderId string, productId string, side Side, baseSize decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{Type: MarketIOC, BaseSize: baseSize})
+}
+
+// PlaceLimitGTC simulates a limit "good till cancelled" order.
+func (p *PaperClient) PlaceLimitGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, postOnly bool) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{
+		Type:       LimitGTC,
+		BaseSize:   size,
+		LimitPrice: price,
+		PostOnly:   postOnly,
+	})
+}
+
+// PlaceLimitGTD simulates a limit "good till date" order.
+func (p *PaperClient) PlaceLimitGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, endTime time.Time, postOnly bool) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{
+		Type:       LimitGTD,
+		BaseSize:   size,
+		LimitPrice: price,
+		EndTime:    endTime,
+		PostOnly:   postOnly,
+	})
+}
+
+// PlaceLimitIOC simulates a limit "immediate or cancel" order.
+func (p *PaperClient) PlaceLimitIOC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{
+		Type:       LimitIOC,
+		BaseSize:   size,
+		LimitPrice: price,
+	})
+}
+
+// PlaceLimitFOK simulates a limit "fill or kill" order.
+func (p *PaperClient) PlaceLimitFOK(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{
+		Type:       LimitFOK,
+		BaseSize:   size,
+		LimitPrice: price,
+	})
+}
+
+// PlaceStopLimitGTC simulates a stop limit "good till cancelled" order.
+func (p *PaperClient) PlaceStopLimitGTC(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopPrice decimal.Decimal, stopDirection StopDirection) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{
+		Type:          StopLimitGTC,
+		BaseSize:      size,
+		LimitPrice:    price,
+		StopPrice:     stopPrice,
+		StopDirection: stopDirection,
+	})
+}
+
+// PlaceStopLimitGTD simulates a stop limit "good till date" order.
+func (p *PaperClient) PlaceStopLimitGTD(clientOrderId string, productId string, side Side, size decimal.Decimal, price decimal.Decimal, stopPrice decimal.Decimal, stopDirection StopDirection, endTime time.Time) (order Order, errorType CreateOrderError, err error) {
+	return p.CreateOrder(clientOrderId, productId, side, OrderConfiguration{
+		Type:          StopLimitGTD,
+		BaseSize:      size,
+		LimitPrice:    price,
+		StopPrice:     stopPrice,
+		StopDirection: stopDirection,
+		EndTime:       endTime,
+	})
+}
+
+// CancelOrders cancels open orders, releasing their held funds. See Client.CancelOrders.
+func (p *PaperClient) CancelOrders(orderIds []string) (cancelErrors map[string]CancelOrderError, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cancelErrors = make(map[string]CancelOrderError)
+	for _, id := range orderIds {
+		o, ok := p.byID[id]
+		switch {
+		case !ok:
+			cancelErrors[id] = UnknownCancelOrder
+		case o.Status != Open:
+			cancelErrors[id] = InvalidCancelRequest
+		default:
+			o.Status = Cancelled
+		}
+	}
+	if len(cancelErrors) > 0 {
+		err = &CancelOrdersFailure{Failures: cancelErrors}
+	}
+	return
+}
+
+// GetOrder returns the current state of a simulated order.
+func (p *PaperClient) GetOrder(id string) (o Order, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	order, ok := p.byID[id]
+	if !ok {
+		err = formatError("get order", ErrNotFound)
+		return
+	}
+	return *order, nil
+}
+
+// UpdateOrder updates an order with the current state of the simulated order.
+func (p *PaperClient) UpdateOrder(order *Order) (err error) {
+	var neworder Order
+	if neworder, err = p.GetOrder(order.ID); err != nil {
+		return
+	}
+	*order = neworder
+	return
+}
+
+// Update fetches the books of the products with open orders, and fills, triggers, or expires the
+// orders as needed.
+func (p *PaperClient) Update() (err error) {
+	p.mu.Lock()
+	products := make(map[string]bool)
+	for _, o := range p.orders {
+		if o.Status == Open {
+			products[o.Product] = true
+		}
+	}
+	p.mu.Unlock()
+
+	books := make(map[string]ProductBook)
+	for id := range products {
+		book, berr := p.book(id)
+		if berr != nil {
+			err = berr // carry on with the other products
+			continue
+		}
+		books[id] = book
+	}
+
+	p.mu.Lock()
+	var fills []Fill
+	now := time.Now()
+	for _, o := range p.orders {
+		if book, ok := books[o.Product]; ok && o.Status == Open {
+			fills = append(fills, p.check(o, book, now)...)
+		}
+	}
+	p.mu.Unlock()
+
+	p.notify(fills)
+	return
+}
+
+// Run calls Update every Interval until ctx is done. Errors are passed to OnError.
+func (p *PaperClient) Run(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = time.Second * 5
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := p.Update(); err != nil && p.OnError != nil {
+			p.OnError(err)
+		}
+	}
+}
+
+// book fetches the book for a product from the market
+func (p *PaperClient) book(productID string) (book ProductBook, err error) {
+	depth := p.BookDepth
+	if depth <= 0 {
+		depth = 100
+	}
+	if book, err = p.market.GetProductBook(productID, depth, decimal.Zero); err != nil {
+		err = formatError("get paper trading book", err)
+		return
+	}
+
+	// make sure the best prices come first
+	sort.SliceStable(book.Bids, func(i, j int) bool { return book.Bids[i].Price.GreaterThan(book.Bids[j].Price) })
+	sort.SliceStable(book.Asks, func(i, j int) bool { return book.Asks[i].Price.LessThan(book.Asks[j].Price) })
+	return
+}
+
+// place matches a new order against the book and records it. If the order is rejected, the reason
+// is returned and nothing is recorded.
+func (p *PaperClient) place(o *Order, book ProductBook) (fills []Fill, reason CreateOrderError) {
+	oc := o.OrderConfiguration
+	switch oc.Type {
+	case MarketIOC:
+		o.Type, o.TimeInForce = Market, ImmediateOrCancel
+	case LimitGTC:
+		o.Type, o.TimeInForce = Limit, GoodUntilCancelled
+	case LimitGTD:
+		o.Type, o.TimeInForce = Limit, GoodUntilDateTime
+	case LimitIOC:
+		o.Type, o.TimeInForce = Limit, ImmediateOrCancel
+	case LimitFOK:
+		o.Type, o.TimeInForce = Limit, FillOrKill
+	case StopLimitGTC:
+		o.Type, o.TimeInForce = StopLimit, GoodUntilCancelled
+	case StopLimitGTD:
+		o.Type, o.TimeInForce = StopLimit, GoodUntilDateTime
+	default:
+		return nil, UnsupportedOrderConfiguration
+	}
+
+	if o.Type == Market {
+		if !oc.BaseSize.IsPositive() && !oc.QuoteSize.IsPositive() {
+			return nil, InvalidRequest
+		}
+	} else if !oc.BaseSize.IsPositive() || !oc.LimitPrice.IsPositive() {
+		return nil, InvalidRequest
+	}
+	if oc.PostOnly && crossesBook(o, book) {
+		return nil, InvalidLimitPricePostOnly
+	}
+
+	// a stop order that hasn't been triggered just waits, with its funds held
+	if o.Type == StopLimit && !stopTriggered(o, book) {
+		o.TriggerStatus = StopPending
+		if p.hold(o).GreaterThan(p.available(p.holdCurrency(o))) {
+			return nil, InsufficientFund
+		}
+		o.Status = Open
+		p.add(o)
+		return
+	}
+	if o.Type == StopLimit {
+		o.TriggerStatus = StopTriggered
+	}
+
+	trades := p.take(o, book)
+	filled := decimal.Zero
+	cost := decimal.Zero // in the currency that is spent
+	for _, t := range trades {
+		filled = filled.Add(t.Size)
+		if o.Side == Buy {
+			cost = cost.Add(t.Price.Mul(t.Size).Mul(decimal.NewFromInt(1).Add(p.TakerFeeRate)))
+		} else {
+			cost = cost.Add(t.Size)
+		}
+	}
+
+	switch {
+	case o.Type == Market && len(trades) == 0:
+		return nil, InvalidNoLiquidity
+	case o.TimeInForce == FillOrKill && filled.LessThan(oc.BaseSize):
+		// not enough liquidity to fill the whole order, so it is cancelled without any fills
+		o.Status = Cancelled
+		p.add(o)
+		return
+	}
+
+	rests := o.Type != Market && o.TimeInForce != ImmediateOrCancel && filled.LessThan(oc.BaseSize)
+	need := cost
+	if rests {
+		rest := *o
+		rest.FilledSize = filled
+		need = need.Add(p.hold(&rest))
+	}
+	if need.GreaterThan(p.available(p.holdCurrency(o))) {
+		return nil, InsufficientFund
+	}
+
+	p.add(o)
+	for _, t := range trades {
+		fills = append(fills, p.fill(o, t.Price, t.Size, LiquidityTaker))
+	}
+	complete := filled.Equal(oc.BaseSize)
+	if o.Type == Market && !oc.BaseSize.IsPositive() {
+		// a market order by quote size is complete unless it used up the book
+		complete = filled.LessThan(sideSize(o, book))
+		if !complete {
+			spent := o.FilledValue
+			if o.Side == Buy {
+				spent = o.TotalValueAfterFees
+			}
+			o.CompletionPercentage = spent.Div(oc.QuoteSize).Mul(decimal.NewFromInt(100))
+		}
+	}
+	switch {
+	case rests:
+		o.Status = Open
+	case complete:
+		o.Status = Filled
+		o.CompletionPercentage = decimal.NewFromInt(100)
+	default:
+		o.Status = Cancelled // the rest of an IOC or market order that the book couldn't fill
+	}
+	return
+}
+
+// check fills, triggers, or expires an open order based on the current book
+func (p *PaperClient) check(o *Order, book ProductBook, now time.Time) (fills []Fill) {
+	oc := o.OrderConfiguration
+	if !oc.EndTime.IsZero() && now.After(oc.EndTime) {
+		o.Status = Expired
+		return
+	}
+
+	if o.TriggerStatus == StopPending {
+		if !stopTriggered(o, book) {
+			return
+		}
+		// the order becomes a limit order, and takes what it can from the book
+		o.TriggerStatus = StopTriggered
+		for _, t := range p.take(o, book) {
+			fills = append(fills, p.fill(o, t.Price, t.Size, LiquidityTaker))
+		}
+	} else if crossesBook(o, book) {
+		// a resting order fills at its own price, up to the size on the other side that crosses it
+		size := decimal.Zero
+		for _, t := range p.take(o, book) {
+			size = size.Add(t.Size)
+		}
+		fills = append(fills, p.fill(o, oc.LimitPrice, size, LiquidityMaker))
+	}
+
+	if !oc.BaseSize.Sub(o.FilledSize).IsPositive() {
+		o.Status = Filled
+		o.CompletionPercentage = decimal.NewFromInt(100)
+	}
+	return
+}
+
+// take returns the levels of the book that an order would take, up to its limit price and
+// remaining size
+func (p *PaperClient) take(o *Order, book ProductBook) (trades []BookLevel) {
+	oc := o.OrderConfiguration
+	levels := book.Asks
+	if o.Side == Sell {
+		levels = book.Bids
+	}
+
+	byBase := oc.BaseSize.IsPositive()
+	base := oc.BaseSize.Sub(o.FilledSize)
+	notional := oc.QuoteSize
+	if o.Side == Buy {
+		// a market buy's quote size includes fees
+		notional = notional.Div(decimal.NewFromInt(1).Add(p.TakerFeeRate))
+	}
+
+	for _, l := range levels {
+		if !oc.LimitPrice.IsZero() && ((o.Side == Buy && l.Price.GreaterThan(oc.LimitPrice)) ||
+			(o.Side == Sell && l.Price.LessThan(oc.LimitPrice))) {
+			break
+		}
+
+		size := l.Size
+		if byBase {
+			size = decimal.Min(size, base)
+			base = base.Sub(size)
+		} else {
+			size = decimal.Min(size, notional.Div(l.Price))
+			notional = notional.Sub(size.Mul(l.Price))
+		}
+		if !size.IsPositive() {
+			break
+		}
+		trades = append(trades, BookLevel{Price: l.Price, Size: size})
+	}
+	return
+}
+
+// sideSize returns the total size on the side of the book that an order takes from
+func sideSize(o *Order, book ProductBook) (size decimal.Decimal) {
+	levels := book.Asks
+	if o.Side == Sell {
+		levels = book.Bids
+	}
+	for _, l := range levels {
+		size = size.Add(l.Size)
+	}
+	return
+}
+
+// fill records a fill for an order, updating the balances and the order
+func (p *PaperClient) fill(o *Order, price, size decimal.Decimal, liquidity LiquidityIndicator) Fill {
+	rate := p.TakerFeeRate
+	if liquidity == LiquidityMaker {
+		rate = p.MakerFeeRate
+	}
+	value := price.Mul(size)
+	fee := value.Mul(rate)
+
+	base, quote := splitProductID(o.Product)
+	if o.Side == Buy {
+		p.balances[quote] = p.balances[quote].Sub(value.Add(fee))
+		p.balances[base] = p.balances[base].Add(size)
+	} else {
+		p.balances[base] = p.balances[base].Sub(size)
+		p.balances[quote] = p.balances[quote].Add(value.Sub(fee))
+	}
+
+	now := time.Now()
+	o.FilledSize = o.FilledSize.Add(size)
+	o.FilledValue = o.FilledValue.Add(value)
+	o.TotalFees = o.TotalFees.Add(fee)
+	o.NumberOfFills = o.NumberOfFills.Add(decimal.NewFromInt(1))
+	o.AverageFilledPrice = o.FilledValue.Div(o.FilledSize)
+	o.LastFillTime = now
+	if o.Side == Buy {
+		o.TotalValueAfterFees = o.FilledValue.Add(o.TotalFees)
+	} else {
+		o.TotalValueAfterFees = o.FilledValue.Sub(o.TotalFees)
+	}
+	if o.OrderConfiguration.BaseSize.IsPositive() {
+		o.CompletionPercentage = o.FilledSize.Div(o.OrderConfiguration.BaseSize).Mul(decimal.NewFromInt(100))
+	}
+
+	f := Fill{
+		ID:                 NewUUID(),
+		TradeID:            NewUUID(),
+		OrderID:            o.ID,
+		TradeTime:          now,
+		Type:               TradeFill,
+		Price:              price,
+		Size:               size,
+		Commission:         fee,
+		ProductID:          o.Product,
+		SequenceTime:       now,
+		LiquidityIndicator: liquidity,
+		Side:               o.Side,
+	}
+	p.fills = append(p.fills, f)
+	return f
+}
+
+// add records a new order
+func (p *PaperClient) add(o *Order) {
+	p.orders = append(p.orders, o)
+	p.byID[o.ID] = o
+	p.byClient[o.ClientOrderID] = o
+}
+
+// hold returns the funds held for the unfilled part of an order. Buys hold enough to pay the
+// taker fee, in case a stop order takes from the book when it is triggered.
+func (p *PaperClient) hold(o *Order) decimal.Decimal {
+	oc := o.OrderConfiguration
+	remaining := oc.BaseSize.Sub(o.FilledSize)
+	if o.Side == Buy {
+		return remaining.Mul(oc.LimitPrice).Mul(decimal.NewFromInt(1).Add(p.TakerFeeRate))
+	}
+	return remaining
+}
+
+// holdCurrency returns the currency that an order spends
+func (p *PaperClient) holdCurrency(o *Order) string {
+	base, quote := splitProductID(o.Product)
+	if o.Side == Buy {
+		return quote
+	}
+	return base
+}
+
+// available returns the balance of a currency that isn't held for open orders
+func (p *PaperClient) available(currency string) decimal.Decimal {
+	available := p.balances[currency]
+	for _, o := range p.orders {
+		if o.Status == Open && p.holdCurrency(o) == currency {
+			available = available.Sub(p.hold(o))
+		}
+	}
+	return available
+}
+
+// notify passes fills to OnFill
+func (p *PaperClient) notify(fills []Fill) {
+	if p.OnFill == nil {
+		return
+	}
+	for _, f := range fills {
+		p.OnFill(f)
+	}
+}
+
+// crossesBook reports whether a limit order's price reaches the best price on the other side of
+// the book
+func crossesBook(o *Order, book ProductBook) bool {
+	limit := o.OrderConfiguration.LimitPrice
+	if o.Side == Buy {
+		return len(book.Asks) > 0 && !book.Asks[0].Price.GreaterThan(limit)
+	}
+	return len(book.Bids) > 0 && !book.Bids[0].Price.LessThan(limit)
+}
+
+// stopTriggered reports whether the mid price of the book has reached a stop order's stop price.
+// If the stop direction isn't set, buys are assumed to stop on the way up, and sells on the way
+// down.
+func stopTriggered(o *Order, book ProductBook) bool {
+	var mid decimal.Decimal
+	switch {
+	case len(book.Bids) > 0 && len(book.Asks) > 0:
+		mid = book.Bids[0].Price.Add(book.Asks[0].Price).Div(decimal.NewFromInt(2))
+	case len(book.Bids) > 0:
+		mid = book.Bids[0].Price
+	case len(book.Asks) > 0:
+		mid = book.Asks[0].Price
+	default:
+		return false
+	}
+
+	oc := o.OrderConfiguration
+	up := oc.StopDirection == StopDirectionUp || (oc.StopDirection == "" && o.Side == Buy)
+	if up {
+		return !mid.LessThan(oc.StopPrice)
+	}
+	return !mid.GreaterThan(oc.StopPrice)
+}
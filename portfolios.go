@@ -0,0 +1,88 @@
+package coinbasetrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("insufficient balance in source portfolio")
+	ErrInvalidPortfolio    = errors.New("invalid portfolio")
+)
+
+// MoveFundsFailure is returned when funds could not be moved between portfolios. Reason is
+// ErrInsufficientBalance or ErrInvalidPortfolio when the cause could be determined, and can be
+// checked with errors.Is. The underlying error (usually an *APIError) is available with errors.As.
+type MoveFundsFailure struct {
+	Reason error
+	Err    error
+}
+
+func (e *MoveFundsFailure) Error() string {
+	if e.Reason == nil {
+		return e.Err.Error()
+	}
+	return e.Reason.Error() + ": " + e.Err.Error()
+}
+
+func (e *MoveFundsFailure) Unwrap() error {
+	return e.Err
+}
+
+func (e *MoveFundsFailure) Is(target error) bool {
+	return e.Reason != nil && target == e.Reason
+}
+
+// MovePortfolioFunds transfers an amount of a currency from one portfolio to another, using the
+// portfolio uuids.
+func (c *Client) MovePortfolioFunds(from, to string, amount decimal.Decimal, currency string) (err error) {
+	if from == "" || to == "" || from == to {
+		return formatError("move portfolio funds", &MoveFundsFailure{ErrInvalidPortfolio, errors.New("source and target portfolios must be different")})
+	}
+	if !amount.IsPositive() {
+		return formatError("move portfolio funds", errors.New("amount must be greater than zero"))
+	}
+
+	wrapper := struct {
+		Funds  Balance `json:"funds"`
+		Source string  `json:"source_portfolio_uuid"`
+		Target string  `json:"target_portfolio_uuid"`
+	}{Balance{amount, currency}, from, to}
+
+	var payload []byte
+	if payload, err = json.Marshal(wrapper); err != nil {
+		err = formatError("move portfolio funds", err)
+		return
+	}
+
+	if _, err = c.makeRequest(context.Background(), Post, movePortfolioFundsEndpoint, url.Values{}, payload, nil, nil); err != nil {
+		err = &MoveFundsFailure{Reason: moveFundsReason(err), Err: err}
+	}
+	return
+}
+
+// moveFundsReason works out why a transfer was rejected from the api error, if possible
+func moveFundsReason(err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	msg := strings.ToLower(apiErr.Message + " " + apiErr.Details)
+	switch {
+	case strings.Contains(msg, "insufficient"):
+		return ErrInsufficientBalance
+	case apiErr.StatusCode == 404 || apiErr.Code == "NOT_FOUND":
+		return ErrInvalidPortfolio
+	case apiErr.Code == "INVALID_ARGUMENT" && strings.Contains(strings.ToLower(apiErr.Details), "portfolio_uuid"):
+		// a malformed source or target uuid; other invalid arguments (e.g. the amount) can
+		// mention the portfolio too, so only the field name is trusted
+		return ErrInvalidPortfolio
+	}
+	return nil
+}
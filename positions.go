@@ -0,0 +1,226 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// PriceSource provides the current price of a product, and false if the price isn't known.
+type PriceSource interface {
+	Price(productID string) (decimal.Decimal, bool)
+}
+
+// PriceFunc lets an ordinary function be used as a PriceSource.
+type PriceFunc func(productID string) (decimal.Decimal, bool)
+
+func (f PriceFunc) Price(productID string) (decimal.Decimal, bool) {
+	return f(productID)
+}
+
+// Position is the user's holding in one product, built up from their fills. Size is positive for
+// a long position and negative for a short one. Realized PnL and fees are in the quote currency,
+// and the PnL already has the fees subtracted.
+type Position struct {
+	ProductID         string
+	Size              decimal.Decimal
+	AverageEntryPrice decimal.Decimal
+	RealizedPnL       decimal.Decimal
+	Fees              decimal.Decimal
+}
+
+// UnrealizedPnL returns the profit or loss that would be made by closing the position at price.
+func (p Position) UnrealizedPnL(price decimal.Decimal) decimal.Decimal {
+	return price.Sub(p.AverageEntryPrice).Mul(p.Size)
+}
+
+// Positions tracks the user's position in each product from their fills, using the average cost
+// of the position to calculate realized profit and loss. Fills can come from ListFills, or be
+// worked out from the order updates on the user websocket channel. It is safe to use from multiple
+// goroutines.
+type Positions struct {
+	mu        sync.Mutex
+	positions map[string]*Position
+	fills     map[string]bool        // ids of fills already added
+	orders    map[string]orderFilled // how much of each order has been filled, from the user channel
+}
+
+type orderFilled struct {
+	size  decimal.Decimal
+	value decimal.Decimal // size * average price
+	fees  decimal.Decimal
+}
+
+// NewPositions creates an empty set of positions.
+func NewPositions() *Positions {
+	return &Positions{
+		positions: make(map[string]*Position),
+		fills:     make(map[string]bool),
+		orders:    make(map[string]orderFilled),
+	}
+}
+
+// LoadFills adds every fill matching the parameters, fetching as many pages as needed. Fills are
+// added oldest first, regardless of the order the api returns them in.
+func (p *Positions) LoadFills(c *Client, params ListFillsParameters) (err error) {
+	var fills []Fill
+	if fills, err = c.allFills(context.Background(), params); err != nil {
+		return
+	}
+
+	for _, f := range fills {
+		p.AddFill(f)
+	}
+	return
+}
+
+// AddFill updates the position for the fill's product. Fills must be added oldest first; a fill
+// that has already been added (by id) is ignored, and false is returned.
+func (p *Positions) AddFill(f Fill) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f.ID != "" {
+		if p.fills[f.ID] {
+			return false
+		}
+		p.fills[f.ID] = true
+	}
+
+	size := f.Size
+	if f.SizeInQuote && !f.Price.IsZero() {
+		size = size.Div(f.Price)
+	}
+	p.apply(f.ProductID, f.Side, size, f.Price, f.Commission)
+	return true
+}
+
+// apply adds a trade to a position
+func (p *Positions) apply(productID string, side Side, size, price, fee decimal.Decimal) {
+	pos, ok := p.positions[productID]
+	if !ok {
+		pos = &Position{ProductID: productID}
+		p.positions[productID] = pos
+	}
+
+	qty := size
+	if side == Sell {
+		qty = qty.Neg()
+	}
+
+	pos.Fees = pos.Fees.Add(fee)
+	pos.RealizedPnL = pos.RealizedPnL.Sub(fee)
+
+	// adding to the position (or opening one) changes the average price
+	if pos.Size.IsZero() || pos.Size.Sign() == qty.Sign() {
+		total := pos.Size.Abs().Add(size)
+		if total.IsPositive() {
+			pos.AverageEntryPrice = pos.Size.Abs().Mul(pos.AverageEntryPrice).Add(size.Mul(price)).Div(total)
+		}
+		pos.Size = pos.Size.Add(qty)
+		return
+	}
+
+	// otherwise some or all of the position is closed, realizing a profit or loss
+	closed := decimal.Min(size, pos.Size.Abs())
+	pnl := price.Sub(pos.AverageEntryPrice).Mul(closed)
+	if pos.Size.IsNegative() {
+		pnl = pnl.Neg()
+	}
+	pos.RealizedPnL = pos.RealizedPnL.Add(pnl)
+
+	before := pos.Size
+	pos.Size = pos.Size.Add(qty)
+	switch {
+	case pos.Size.IsZero():
+		pos.AverageEntryPrice = decimal.Zero
+	case pos.Size.Sign() != before.Sign():
+		// the trade was bigger than the position, so the rest opens a new one the other way
+		pos.AverageEntryPrice = price
+	}
+}
+
+// ApplyUserMessage works out new fills from the order updates in a user channel message. For the
+// orders in the snapshot sent when subscribing, only their current progress is recorded, so fills
+// that have already been loaded aren't counted twice. An order first seen in an update is counted
+// from nothing filled, so the fills of an order that was filled as soon as it was placed are kept.
+// Messages from other channels are ignored.
+func (p *Positions) ApplyUserMessage(msg WebsocketMessage) (err error) {
+	if msg.Channel != UserChannel {
+		return
+	}
+
+	var events []UserEvent
+	if events, err = msg.UserEvents(); err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, e := range events {
+		for _, o := range e.Orders {
+			now := orderFilled{
+				size:  o.FilledSize,
+				value: o.FilledSize.Mul(o.AverageFilledPrice),
+				fees:  o.TotalFees,
+			}
+
+			// an order that hasn't been seen before had nothing filled before this update
+			prev := p.orders[o.ID]
+			p.orders[o.ID] = now
+			if o.Status.Terminal() {
+				delete(p.orders, o.ID)
+			}
+			if e.Type == "snapshot" {
+				continue
+			}
+
+			size := now.size.Sub(prev.size)
+			if !size.IsPositive() {
+				continue
+			}
+			price := now.value.Sub(prev.value).Div(size)
+			p.apply(o.Product, o.Side, size, price, now.fees.Sub(prev.fees))
+		}
+	}
+	return
+}
+
+// Get returns the position for a product.
+func (p *Positions) Get(productID string) Position {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pos, ok := p.positions[productID]; ok {
+		return *pos
+	}
+	return Position{ProductID: productID}
+}
+
+// All returns every position that has been traded, sorted by product.
+func (p *Positions) All() (all []Position) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pos := range p.positions {
+		all = append(all, *pos)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ProductID < all[j].ProductID })
+	return
+}
+
+// UnrealizedPnL returns the unrealized profit or loss of a position at the price given by prices,
+// and false if the price isn't available.
+func (p *Positions) UnrealizedPnL(productID string, prices PriceSource) (pnl decimal.Decimal, ok bool) {
+	pos := p.Get(productID)
+	if pos.Size.IsZero() {
+		return decimal.Zero, true
+	}
+
+	var price decimal.Decimal
+	if price, ok = prices.Price(productID); !ok {
+		return
+	}
+	return pos.UnrealizedPnL(price), true
+}
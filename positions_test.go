@@ -0,0 +1,79 @@
+package coinbasetrade_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+)
+
+// userMessage builds a user channel message with one event, with each order given as
+// "id status cumulative_quantity avg_price"
+func userMessage(eventType string, orders ...string) coinbasetrade.WebsocketMessage {
+	var list []string
+	for _, o := range orders {
+		f := strings.Fields(o)
+		list = append(list, fmt.Sprintf(`{"order_id":%q,"product_id":"BTC-USD","order_side":"BUY","status":%q,"cumulative_quantity":%q,"avg_price":%q,"total_fees":"0"}`, f[0], f[1], f[2], f[3]))
+	}
+	events := fmt.Sprintf(`[{"type":%q,"orders":[%s]}]`, eventType, strings.Join(list, ","))
+	return coinbasetrade.WebsocketMessage{Channel: coinbasetrade.UserChannel, Events: []byte(events)}
+}
+
+func TestPositionsApplyUserMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []coinbasetrade.WebsocketMessage
+		want     string
+	}{
+		{
+			name: "snapshot orders are already counted",
+			messages: []coinbasetrade.WebsocketMessage{
+				userMessage("snapshot", "a FILLED 1 100", "b OPEN 0.5 100"),
+			},
+			want: "0",
+		},
+		{
+			name: "a snapshot order counts what is filled after the snapshot",
+			messages: []coinbasetrade.WebsocketMessage{
+				userMessage("snapshot", "b OPEN 0.5 100"),
+				userMessage("update", "b FILLED 1 100"),
+			},
+			want: "0.5",
+		},
+		{
+			name: "a new order filled as soon as it is placed",
+			messages: []coinbasetrade.WebsocketMessage{
+				userMessage("snapshot"),
+				userMessage("update", "c FILLED 1 100"),
+			},
+			want: "1",
+		},
+		{
+			name: "a new order filled over several updates",
+			messages: []coinbasetrade.WebsocketMessage{
+				userMessage("snapshot"),
+				userMessage("update", "d OPEN 0.25 100"),
+				userMessage("update", "d OPEN 0.75 100"),
+				userMessage("update", "d FILLED 1 100"),
+			},
+			want: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := coinbasetrade.NewPositions()
+			for _, msg := range tt.messages {
+				if err := p.ApplyUserMessage(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := p.Get("BTC-USD").Size; !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Size = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package coinbasetrade
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price is the latest price of a product from the ticker channel.
+type Price struct {
+	Price   decimal.Decimal // the last trade
+	BestBid decimal.Decimal
+	BestAsk decimal.Decimal
+	Updated time.Time // when the server sent the ticker
+}
+
+// PriceCache holds the latest price of a set of products, from the ticker (or ticker_batch)
+// channel, so strategy code can read current prices without waiting on the network. Reading a
+// price doesn't allocate. It is safe to use from multiple goroutines.
+type PriceCache struct {
+	ProductIDs []string
+
+	mu     sync.RWMutex
+	prices map[string]Price
+}
+
+// NewPriceCache creates an empty cache for the given products. Call Subscribe, and pass every
+// message from the websocket to Apply.
+func NewPriceCache(productIDs ...string) *PriceCache {
+	return &PriceCache{
+		ProductIDs: productIDs,
+		prices:     make(map[string]Price, len(productIDs)),
+	}
+}
+
+// Subscribe subscribes the websocket to the ticker channel for the cache's products.
+func (pc *PriceCache) Subscribe(ws *Websocket) error {
+	return ws.Subscribe(TickerChannel, pc.ProductIDs...)
+}
+
+// Apply updates the cache from a ticker or ticker_batch message. Other messages are ignored.
+func (pc *PriceCache) Apply(msg WebsocketMessage) error {
+	if msg.Channel != TickerChannel && msg.Channel != TickerBatchChannel {
+		return nil
+	}
+
+	events, err := msg.TickerEvents()
+	if err != nil {
+		return err
+	}
+
+	updated := msg.Timestamp
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for _, e := range events {
+		for _, t := range e.Tickers {
+			// a batch can arrive after a newer single ticker
+			if prev, ok := pc.prices[t.ProductID]; ok && prev.Updated.After(updated) {
+				continue
+			}
+			pc.prices[t.ProductID] = Price{Price: t.Price, BestBid: t.BestBid, BestAsk: t.BestAsk, Updated: updated}
+		}
+	}
+	return nil
+}
+
+// GetPrice returns the latest price of a product, and false if no ticker has been received for it.
+// Check Updated to see how old it is.
+func (pc *PriceCache) GetPrice(productID string) (p Price, ok bool) {
+	pc.mu.RLock()
+	p, ok = pc.prices[productID]
+	pc.mu.RUnlock()
+	return
+}
@@ -1,9 +1,14 @@
 package coinbasetrade
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"iter"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -14,6 +19,26 @@ type ProductType string
 const (
 	UnknownProductType ProductType = "UNKNOWN_PRODUCT_TYPE"
 	ProductTypeSpot    ProductType = "SPOT"
+	ProductTypeFuture  ProductType = "FUTURE"
+)
+
+// ContractExpiryType is the kind of expiry a futures contract has.
+type ContractExpiryType string
+
+const (
+	UnknownContractExpiryType ContractExpiryType = "UNKNOWN_CONTRACT_EXPIRY_TYPE"
+	ContractExpiring          ContractExpiryType = "EXPIRING"
+	ContractPerpetual         ContractExpiryType = "PERPETUAL"
+)
+
+// ExpiringContractStatus filters futures products by whether they have expired.
+type ExpiringContractStatus string
+
+const (
+	UnknownExpiringContractStatus ExpiringContractStatus = "UNKNOWN_EXPIRING_CONTRACT_STATUS"
+	ContractStatusUnexpired       ExpiringContractStatus = "STATUS_UNEXPIRED"
+	ContractStatusExpired         ExpiringContractStatus = "STATUS_EXPIRED"
+	ContractStatusAll             ExpiringContractStatus = "STATUS_ALL"
 )
 
 type Granularity string
@@ -27,8 +52,34 @@ const (
 	TwoHour       Granularity = "TWO_HOUR"
 	SixHour       Granularity = "SIX_HOUR"
 	OneDay        Granularity = "ONE_DAY"
+
+	maxCandlesPerRequest = 350 // the most candles the api will return for one request
 )
 
+// Duration returns the length of time covered by one candle of this granularity, or zero if the
+// granularity isn't recognized.
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case OneMinute:
+		return time.Minute
+	case FiveMinute:
+		return time.Minute * 5
+	case FifteenMinute:
+		return time.Minute * 15
+	case ThirtyMinute:
+		return time.Minute * 30
+	case OneHour:
+		return time.Hour
+	case TwoHour:
+		return time.Hour * 2
+	case SixHour:
+		return time.Hour * 6
+	case OneDay:
+		return time.Hour * 24
+	}
+	return 0
+}
+
 type Product struct {
 	ID                        string          `json:"product_id"`
 	Price                     decimal.Decimal `json:"price"`
@@ -55,42 +106,117 @@ type Product struct {
 	ProductType               string          `json:"product_type"`
 	QuoteCurrencyID           string          `json:"quote_currency_id"`
 	BaseCurrencyID            string          `json:"base_currency_id"`
+	PriceIncrement            decimal.Decimal `json:"price_increment"`
+	BaseDisplaySymbol         string          `json:"base_display_symbol"`
+	QuoteDisplaySymbol        string          `json:"quote_display_symbol"`
+	Alias                     string          `json:"alias"`    // another product that trades on the same book, e.g. BTC-USDC for BTC-USD
+	AliasTo                   []string        `json:"alias_to"` // products that are aliases of this one
+	ViewOnly                  bool            `json:"view_only"`
+	ProductVenue              string          `json:"product_venue"` // e.g. "CBE" for the Coinbase exchange, "FCM" for futures
+
+	// only populated for futures products
+	FCMTradingSessionDetails *FCMTradingSessionDetails `json:"fcm_trading_session_details"`
+	FutureProductDetails     *FutureProductDetails     `json:"future_product_details"`
+
+	Raw json.RawMessage `json:"-"` // the product as it was received, if the client's KeepRaw is set
+
 	// currently appears to not be populated by CB:
 	// MidMarketPrice            decimal.Decimal `json:"mid_market_price"`
 }
 
+// priceIncrement returns the increment prices must be a multiple of
+func (p Product) priceIncrement() decimal.Decimal {
+	if p.PriceIncrement.IsPositive() {
+		return p.PriceIncrement
+	}
+	return p.QuoteIncrement
+}
+
 type ProductList struct {
 	Products []Product `json:"products"`
-	Pagination
+	Pagination[ListProductsParameters, Product]
+}
+
+// NextPage replaces the products in the list with the next page of results, or appends the page to
+// them if Accumulate is set.
+func (l *ProductList) NextPage() error {
+	_, err := l.FetchPage()
+	return err
+}
+
+// FetchPage works like NextPage, and also returns the products in the page that was fetched. It returns
+// no products once there are no more pages.
+func (l *ProductList) FetchPage() ([]Product, error) {
+	return l.nextPage(context.Background(), &l.Products)
+}
+
+// All returns the products in the current page and every page after it, up to the given limits.
+func (l *ProductList) All(ctx context.Context, limits AllLimits) ([]Product, error) {
+	return l.all(ctx, limits, &l.Products)
 }
 
 type ListProductsParameters struct {
-	Limit int         `cbt:"limit"`
-	Type  ProductType `cbt:"product_type"`
+	Limit                  int                    `cbt:"limit"`
+	Type                   ProductType            `cbt:"product_type"`
+	ProductIDs             []string               `cbt:"product_ids"`
+	ContractExpiryType     ContractExpiryType     `cbt:"contract_expiry_type"`
+	ExpiringContractStatus ExpiringContractStatus `cbt:"expiring_contract_status"`
+	GetTradabilityStatus   bool                   `cbt:"get_tradability_status"` // include whether each product can be traded (authenticated clients only)
+	Offset                 int                    // start listing from an offset saved from an earlier list
 }
 
 // ListProducts returns a list of products based on the parameters you provide.
 func (c *Client) ListProducts(params ListProductsParameters) (l ProductList, err error) {
+	l.Pagination = Pagination[ListProductsParameters, Product]{pager: c.productsPager(params)}
+
+	err = l.NextPage()
+	return
+}
+
+// IterProducts returns an iterator over every product matching the parameters, fetching pages as
+// needed.
+func (c *Client) IterProducts(params ListProductsParameters) *Iterator[Product] {
+	return newIterator(c.productsPager(params))
+}
+
+// StreamProducts sends every product matching the parameters on a channel as the pages are fetched. See
+// Iterator.Stream.
+func (c *Client) StreamProducts(ctx context.Context, params ListProductsParameters) (<-chan Product, <-chan error) {
+	return c.IterProducts(params).Stream(ctx)
+}
+
+// Products returns every product matching the parameters as a sequence for use with range, fetching pages as needed:
+//
+//	for p, err := range client.Products(ctx, params) {
+//		...
+//	}
+func (c *Client) Products(ctx context.Context, params ListProductsParameters) iter.Seq2[Product, error] {
+	return c.IterProducts(params).Seq(ctx)
+}
+
+func (c *Client) productsPager(params ListProductsParameters) pager[ListProductsParameters, Product] {
 	if params.Limit <= 0 {
 		params.Limit = 100
 	}
-	l.Pagination = Pagination{
-		client:     c,
-		parent:     &l,
-		parameters: params,
-		limit:      params.Limit,
+
+	return pager[ListProductsParameters, Product]{
+		client:    c,
+		params:    params,
+		pageState: pageState{offsetPaging: true, limit: params.Limit, offset: params.Offset},
 
 		method:   Get,
-		endpoint: listProductsEndpoint,
+		endpoint: c.marketEndpoint(listProductsEndpoint),
+		key:      "products",
 	}
-
-	err = l.NextPage()
-	return
 }
 
 // GetProduct takes a product ID and returns a Product object.
 func (c *Client) GetProduct(id string) (prod Product, err error) {
-	_, err = c.makeRequest(Get, fmt.Sprintf(getProductEndpoint, id), url.Values{}, []byte{}, &prod, nil)
+	var data []byte
+	if data, err = c.makeRequest(context.Background(), Get, fmt.Sprintf(c.marketEndpoint(getProductEndpoint), id), url.Values{}, []byte{}, &prod, nil); err != nil {
+		return
+	}
+	prod.Raw = c.rawItem(data, "")
 	return
 }
 
@@ -106,10 +232,74 @@ type Candle struct {
 	Volume decimal.Decimal `json:"volume"`
 }
 
+// UnmarshalJSON fills in StartTime and StartUnix from the start timestamp, which may be sent as
+// either a string or a number, so candles decoded from any source are complete.
+func (c *Candle) UnmarshalJSON(data []byte) error {
+	type candle Candle // avoids calling this method again
+	aux := struct {
+		*candle
+		Start json.RawMessage `json:"start"`
+	}{candle: (*candle)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if len(aux.Start) > 0 {
+		c.StartString = strings.Trim(string(aux.Start), `"`)
+		c.StartUnix, _ = strconv.ParseInt(c.StartString, 10, 64)
+		c.StartTime = time.Unix(c.StartUnix, 0)
+	}
+	return nil
+}
+
 // GetProductCandles takes a product ID, start and end times for the period you want to see, and the granularity
 // of data that should be returned.
 // The start time for each interval is included in 3 formats for convenience: string, int64, and time.Time.
+// The api only returns 350 candles per request, so longer periods are split into several requests and the
+// results combined. Candles are returned newest first, as the api sends them.
 func (c *Client) GetProductCandles(id string, start, end time.Time, granularity Granularity) (candles []Candle, err error) {
+	chunks := candleChunks(start, end, granularity)
+
+	// request the newest chunk first, so the results are already in order
+	seen := make(map[int64]bool)
+	for i := len(chunks) - 1; i >= 0; i-- {
+		var chunk []Candle
+		if chunk, err = c.getCandles(context.Background(), id, chunks[i][0], chunks[i][1], granularity); err != nil {
+			return
+		}
+		for _, v := range chunk {
+			if !seen[v.StartUnix] {
+				seen[v.StartUnix] = true
+				candles = append(candles, v)
+			}
+		}
+	}
+
+	// chunks may overlap slightly at the edges, so make sure the order is right
+	sort.SliceStable(candles, func(i, j int) bool { return candles[i].StartUnix > candles[j].StartUnix })
+	return
+}
+
+// candleChunks splits a period into ranges that each return no more than the maximum number of candles
+func candleChunks(start, end time.Time, granularity Granularity) (chunks [][2]time.Time) {
+	span := granularity.Duration() * maxCandlesPerRequest
+	if span == 0 {
+		// let the api decide what to do with an unknown granularity
+		return [][2]time.Time{{start, end}}
+	}
+
+	for s := start; ; s = s.Add(span) {
+		e := s.Add(span - granularity.Duration())
+		if !e.Before(end) {
+			return append(chunks, [2]time.Time{s, end})
+		}
+		chunks = append(chunks, [2]time.Time{s, e})
+	}
+}
+
+// getCandles makes a single request for candles
+func (c *Client) getCandles(ctx context.Context, id string, start, end time.Time, granularity Granularity) (candles []Candle, err error) {
 	// wrapper for the api response
 	var res struct {
 		Candles []Candle `json:"candles"`
@@ -121,14 +311,8 @@ func (c *Client) GetProductCandles(id string, start, end time.Time, granularity
 	query.Add("end", fmt.Sprintf("%d", end.Unix()))
 	query.Add("granularity", string(granularity))
 
-	_, err = c.makeRequest(Get, fmt.Sprintf(getProductCandlesEndpoint, id), query, []byte{}, &res, nil)
+	_, err = c.makeRequest(ctx, Get, fmt.Sprintf(c.marketEndpoint(getProductCandlesEndpoint), id), query, []byte{}, &res, nil)
 	candles = res.Candles
-
-	for i, v := range candles {
-		candles[i].StartUnix, _ = strconv.ParseInt(v.StartString, 10, 64)
-		candles[i].StartTime = time.Unix(candles[i].StartUnix, 0)
-	}
-
 	return
 }
 
@@ -139,9 +323,25 @@ type Trade struct {
 	Size      decimal.Decimal `json:"size"`
 	Time      time.Time       `json:"time"`
 	Side      Side            `json:"side"`
-	// As of February 2023, these are included in the api response but only contain empty values:
-	// Bid       decimal.Decimal `json:"bid"`
-	// Ask       decimal.Decimal `json:"ask"`
+	Bid       decimal.Decimal `json:"bid"` // often left empty by the api, in which case it is zero
+	Ask       decimal.Decimal `json:"ask"` // often left empty by the api, in which case it is zero
+}
+
+// UnmarshalJSON allows the bid and ask to be empty.
+func (t *Trade) UnmarshalJSON(data []byte) error {
+	type trade Trade // avoids calling this method again
+	aux := struct {
+		*trade
+		Bid string `json:"bid"`
+		Ask string `json:"ask"`
+	}{trade: (*trade)(t)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	t.Bid = parseOptionalDecimal(aux.Bid)
+	t.Ask = parseOptionalDecimal(aux.Ask)
+	return nil
 }
 
 type MarketTrades struct {
@@ -150,13 +350,156 @@ type MarketTrades struct {
 	BestAsk decimal.Decimal `json:"best_ask"`
 }
 
+// MarketTradesParameters narrows down the trades returned by GetMarketTradesRange. Trades are
+// returned newest first, so Limit trades are taken back from End (or the current time).
+type MarketTradesParameters struct {
+	Limit int
+	Start time.Time
+	End   time.Time
+}
+
 // GetMarketTrades will return the current best bid and ask, plus a slice of the last `n` trades
 // from the ticker
 func (c *Client) GetMarketTrades(product string, n int) (market MarketTrades, err error) {
+	return c.GetMarketTradesRange(product, MarketTradesParameters{Limit: n})
+}
 
+// GetMarketTradesRange returns the current best bid and ask, plus the trades within a time range.
+func (c *Client) GetMarketTradesRange(product string, params MarketTradesParameters) (market MarketTrades, err error) {
+	// this endpoint takes unix timestamps rather than the usual format
 	query := make(url.Values)
-	query.Add("limit", fmt.Sprintf("%d", n))
+	query.Add("limit", fmt.Sprintf("%d", params.Limit))
+	if !params.Start.IsZero() {
+		query.Add("start", fmt.Sprintf("%d", params.Start.Unix()))
+	}
+	if !params.End.IsZero() {
+		query.Add("end", fmt.Sprintf("%d", params.End.Unix()))
+	}
 
-	_, err = c.makeRequest(Get, fmt.Sprintf(getMarketTradesEndpoint, product), query, []byte{}, &market, nil)
+	_, err = c.makeRequest(context.Background(), Get, fmt.Sprintf(c.marketEndpoint(getMarketTradesEndpoint), product), query, []byte{}, &market, nil)
 	return
 }
+
+// MarketTradePager pages backwards through a product's trades, newest first. The api has no
+// cursor for trades, so each page ends where the one before it started.
+type MarketTradePager struct {
+	client  *Client
+	product string
+	params  MarketTradesParameters
+	limit   int             // the limit for the next request, raised while one second fills whole pages
+	seen    map[string]bool // ids of trades at the boundary with the previous page
+	done    bool
+
+	BestBid decimal.Decimal // from the most recent page
+	BestAsk decimal.Decimal // from the most recent page
+}
+
+// PageMarketTrades creates a pager for a product's trades. Each page has up to params.Limit trades
+// (100 if not set), starting at params.End (or now) and going back as far as params.Start.
+func (c *Client) PageMarketTrades(product string, params MarketTradesParameters) *MarketTradePager {
+	if params.Limit <= 0 {
+		params.Limit = 100
+	}
+	return &MarketTradePager{
+		client:  c,
+		product: product,
+		params:  params,
+		limit:   params.Limit,
+		seen:    make(map[string]bool),
+	}
+}
+
+// Next returns the next page of older trades. When there are no more, it returns an empty page.
+// If more trades were made in one second than fit in a page, later pages are made larger until
+// they reach past that second.
+func (p *MarketTradePager) Next() (trades []Trade, err error) {
+	for !p.done && len(trades) == 0 {
+		params := p.params
+		params.Limit = p.limit
+
+		var m MarketTrades
+		if m, err = p.client.GetMarketTradesRange(p.product, params); err != nil {
+			return
+		}
+		p.BestBid, p.BestAsk = m.BestBid, m.BestAsk
+
+		if len(m.Trades) == 0 {
+			p.done = true
+			return
+		}
+
+		// the time range is in whole seconds, so trades at the boundary can be returned twice
+		oldest := m.Trades[0].Time
+		for _, t := range m.Trades {
+			if t.Time.Before(oldest) {
+				oldest = t.Time
+			}
+			if !p.seen[t.ID] {
+				trades = append(trades, t)
+			}
+		}
+
+		next := oldest.Truncate(time.Second)
+		if !next.Equal(p.params.End) {
+			// a new boundary, so only trades in its second can be returned again
+			p.seen, p.limit = make(map[string]bool), p.params.Limit
+		}
+		for _, t := range m.Trades {
+			if !t.Time.Before(next) {
+				p.seen[t.ID] = true
+			}
+		}
+		if len(trades) == 0 {
+			if len(m.Trades) < p.limit {
+				// every trade in the boundary second has been returned, so skip past it
+				next = next.Add(-time.Second)
+			} else {
+				// the boundary second filled the page, so ask for more to reach the rest of it
+				p.limit += p.params.Limit
+			}
+		}
+		p.params.End = next
+
+		if !p.params.Start.IsZero() && !p.params.End.After(p.params.Start) {
+			p.done = true
+		}
+	}
+	return
+}
+
+// ProductBook is a snapshot of the order book for one product.
+type ProductBook struct {
+	ProductID string      `json:"product_id"`
+	Bids      []BookLevel `json:"bids"`
+	Asks      []BookLevel `json:"asks"`
+	Time      time.Time   `json:"time"`
+}
+
+// GetProductBook returns the top `limit` bids and asks for a product. If aggregationIncrement is
+// non-zero, the server will combine price levels into buckets of that size.
+func (c *Client) GetProductBook(productID string, limit int, aggregationIncrement decimal.Decimal) (book ProductBook, err error) {
+	wrapper := &struct {
+		Book *ProductBook `json:"pricebook"`
+	}{&book}
+
+	query := make(url.Values)
+	query.Add("product_id", productID)
+	if limit > 0 {
+		query.Add("limit", fmt.Sprintf("%d", limit))
+	}
+	if !aggregationIncrement.IsZero() {
+		query.Add("aggregation_price_increment", aggregationIncrement.String())
+	}
+
+	_, err = c.makeRequest(context.Background(), Get, c.marketEndpoint(getProductBookEndpoint), query, []byte{}, wrapper, nil)
+	return
+}
+
+// Aggregate returns a copy of the book with price levels combined into buckets of the given
+// increment. Bids are rounded down and asks are rounded up, so each bucket's price is the worst
+// price of the levels in it.
+func (b ProductBook) Aggregate(increment decimal.Decimal) ProductBook {
+	b.Bids = AggregateLevels(b.Bids, increment, false)
+	b.Asks = AggregateLevels(b.Asks, increment, true)
+	return b
+}
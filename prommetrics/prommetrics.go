@@ -0,0 +1,94 @@
+// Package prommetrics reports the metrics of a coinbasetrade.Client to Prometheus. It is a module of
+// its own, so only programs that use it depend on the Prometheus client.
+//
+//	m := prommetrics.New(prometheus.DefaultRegisterer, "coinbase")
+//	client := coinbasetrade.NewClient(&coinbasetrade.ClientConfig{Metrics: m, ...})
+package prommetrics
+
+import (
+	"strconv"
+	"time"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements coinbasetrade.Metrics with Prometheus collectors.
+type Metrics struct {
+	Requests            *prometheus.CounterVec   // requests by method, endpoint, and status
+	RequestDuration     *prometheus.HistogramVec // request latency in seconds by method and endpoint
+	RateLimitWait       *prometheus.HistogramVec // time spent waiting for the limiter in seconds by endpoint
+	Retries             *prometheus.CounterVec   // retries by method and endpoint
+	WebsocketReconnects prometheus.Counter       // websocket reconnect attempts
+}
+
+var _ coinbasetrade.Metrics = (*Metrics)(nil)
+
+// New creates the collectors, with names starting with namespace (if not empty), and registers
+// them with reg (if not nil). It panics if they are already registered, like
+// prometheus.MustRegister.
+func New(reg prometheus.Registerer, namespace string) *Metrics {
+	m := &Metrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "API requests by method, endpoint, and status code (\"error\" if there was no response).",
+		}, []string{"method", "endpoint", "status"}),
+
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "API request latency by method and endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+
+		RateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_wait_seconds",
+			Help:      "Time requests spent waiting for the rate limiter, by endpoint.",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}, []string{"endpoint"}),
+
+		Retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Failed API requests that were retried, by method and endpoint.",
+		}, []string{"method", "endpoint"}),
+
+		WebsocketReconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "websocket_reconnects_total",
+			Help:      "Attempts to reconnect a websocket.",
+		}),
+	}
+
+	if reg != nil {
+		reg.MustRegister(m.Requests, m.RequestDuration, m.RateLimitWait, m.Retries, m.WebsocketReconnects)
+	}
+	return m
+}
+
+// ObserveRequest counts a request and records its latency.
+func (m *Metrics) ObserveRequest(method coinbasetrade.Method, endpoint string, status int, duration time.Duration) {
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	m.Requests.WithLabelValues(string(method), endpoint, code).Inc()
+	m.RequestDuration.WithLabelValues(string(method), endpoint).Observe(duration.Seconds())
+}
+
+// ObserveRateLimitWait records how long a request waited for the limiter.
+func (m *Metrics) ObserveRateLimitWait(endpoint string, wait time.Duration) {
+	m.RateLimitWait.WithLabelValues(endpoint).Observe(wait.Seconds())
+}
+
+// ObserveRetry counts a retry.
+func (m *Metrics) ObserveRetry(method coinbasetrade.Method, endpoint string, attempt int) {
+	m.Retries.WithLabelValues(string(method), endpoint).Inc()
+}
+
+// ObserveWebsocketReconnect counts a reconnect attempt.
+func (m *Metrics) ObserveWebsocketReconnect(attempt int) {
+	m.WebsocketReconnects.Inc()
+}
@@ -0,0 +1,101 @@
+package coinbasetrade
+
+import (
+	"context"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PublicClient fetches market data from the public endpoints, which don't require an API key.
+// Public endpoints have lower rate limits and may return slightly delayed data.
+type PublicClient struct {
+	client *Client
+}
+
+// NewPublicClient creates a client for the public market data endpoints. Any credentials in the
+// config or environment are ignored; only the host, path, and http/limiter options are used.
+func NewPublicClient(config *ClientConfig) *PublicClient {
+	c := NewClient(config)
+	c.public = true
+
+	// all requests are public, so a Limiter given for the client is used for them
+	if config != nil && config.Limiter != nil && config.PublicLimiter == nil {
+		c.PublicLimiter = config.Limiter
+	}
+	return &PublicClient{client: c}
+}
+
+// marketEndpoint returns the public version of a market data endpoint if the client is public, or
+// gets its market data from the public endpoints
+func (c *Client) marketEndpoint(endpoint string) string {
+	if c.public || c.publicMarketData {
+		return publicMarketPrefix + endpoint
+	}
+	return endpoint
+}
+
+// EnableDebug turns on some extra logging information
+func (p *PublicClient) EnableDebug() {
+	p.client.EnableDebug()
+}
+
+// EnableDebugDump turns on debug logging, and writes every request and response in full to w.
+func (p *PublicClient) EnableDebugDump(w io.Writer) {
+	p.client.EnableDebugDump(w)
+}
+
+// ListProducts returns a list of products based on the parameters you provide.
+func (p *PublicClient) ListProducts(params ListProductsParameters) (ProductList, error) {
+	return p.client.ListProducts(params)
+}
+
+// IterProducts returns an iterator over every product matching the parameters, fetching pages as
+// needed.
+func (p *PublicClient) IterProducts(params ListProductsParameters) *Iterator[Product] {
+	return p.client.IterProducts(params)
+}
+
+// StreamProducts sends every product matching the parameters on a channel as the pages are
+// fetched. See Iterator.Stream.
+func (p *PublicClient) StreamProducts(ctx context.Context, params ListProductsParameters) (<-chan Product, <-chan error) {
+	return p.client.StreamProducts(ctx, params)
+}
+
+// Products returns every product matching the parameters as a sequence for use with range. See
+// Client.Products.
+func (p *PublicClient) Products(ctx context.Context, params ListProductsParameters) iter.Seq2[Product, error] {
+	return p.client.Products(ctx, params)
+}
+
+// GetProduct takes a product ID and returns a Product object.
+func (p *PublicClient) GetProduct(id string) (Product, error) {
+	return p.client.GetProduct(id)
+}
+
+// GetProductCandles returns historical market data for a product. See Client.GetProductCandles.
+func (p *PublicClient) GetProductCandles(id string, start, end time.Time, granularity Granularity) ([]Candle, error) {
+	return p.client.GetProductCandles(id, start, end, granularity)
+}
+
+// GetMarketTrades returns the current best bid and ask, plus a slice of the last `n` trades.
+func (p *PublicClient) GetMarketTrades(product string, n int) (MarketTrades, error) {
+	return p.client.GetMarketTrades(product, n)
+}
+
+// GetMarketTradesRange returns the current best bid and ask, plus the trades within a time range.
+func (p *PublicClient) GetMarketTradesRange(product string, params MarketTradesParameters) (MarketTrades, error) {
+	return p.client.GetMarketTradesRange(product, params)
+}
+
+// PageMarketTrades creates a pager for a product's trades. See Client.PageMarketTrades.
+func (p *PublicClient) PageMarketTrades(product string, params MarketTradesParameters) *MarketTradePager {
+	return p.client.PageMarketTrades(product, params)
+}
+
+// GetProductBook returns the top `limit` bids and asks for a product. See Client.GetProductBook.
+func (p *PublicClient) GetProductBook(productID string, limit int, aggregationIncrement decimal.Decimal) (ProductBook, error) {
+	return p.client.GetProductBook(productID, limit, aggregationIncrement)
+}
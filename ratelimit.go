@@ -0,0 +1,248 @@
+package coinbasetrade
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitStatus is the request budget reported by the server in the headers of the most recent
+// response. Fields the server didn't include are left at their zero value.
+type RateLimitStatus struct {
+	Limit     int       // requests allowed in the current window
+	Remaining int       // requests left in the current window
+	Reset     time.Time // when the window resets
+	Updated   time.Time // when this status was received; zero if no rate limit headers have been seen
+}
+
+// rateLimitState holds the latest status, which can be read while requests are being made
+type rateLimitState struct {
+	mu     sync.Mutex
+	status RateLimitStatus
+}
+
+// RateLimit returns the rate limit budget from the most recent response from a private endpoint,
+// so callers can slow down before the server starts rejecting requests.
+func (c *Client) RateLimit() RateLimitStatus {
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	return c.rateLimit.status
+}
+
+// PublicRateLimit returns the rate limit budget from the most recent response from a public market
+// data endpoint, which is counted separately from the private endpoints.
+func (c *Client) PublicRateLimit() RateLimitStatus {
+	c.publicRateLimit.mu.Lock()
+	defer c.publicRateLimit.mu.Unlock()
+	return c.publicRateLimit.status
+}
+
+// isPublicEndpoint reports whether an endpoint is one of the public market data endpoints, which
+// have their own rate limit and don't need to be signed
+func isPublicEndpoint(endpoint string) bool {
+	return strings.HasPrefix(endpoint, publicMarketPrefix+"/")
+}
+
+// limiter returns the limiter for an endpoint's rate limit, and its stats
+func (c *Client) limiter(endpoint string) (Limiter, *limiterStats) {
+	if isPublicEndpoint(endpoint) {
+		return c.PublicLimiter, c.publicLimiterStats
+	}
+	return c.Limiter, c.limiterStats
+}
+
+// updateRateLimit records any rate limit headers included in a response
+func (c *Client) updateRateLimit(endpoint string, h http.Header) {
+	limit, limitErr := strconv.Atoi(h.Get("X-Ratelimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(h.Get("X-Ratelimit-Remaining"))
+	reset, resetErr := strconv.ParseInt(h.Get("X-Ratelimit-Reset"), 10, 64)
+	if limitErr != nil && remainingErr != nil && resetErr != nil {
+		return
+	}
+
+	now := time.Now()
+	status := RateLimitStatus{Updated: now}
+	if limitErr == nil {
+		status.Limit = limit
+	}
+	if remainingErr == nil {
+		status.Remaining = remaining
+	}
+	if resetErr == nil {
+		// the reset can be sent either as a unix timestamp or as seconds from now
+		if reset > 1e9 {
+			status.Reset = time.Unix(reset, 0)
+		} else {
+			status.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	state := c.rateLimit
+	if isPublicEndpoint(endpoint) {
+		state = c.publicRateLimit
+	}
+	state.mu.Lock()
+	state.status = status
+	state.mu.Unlock()
+}
+
+// retryAfter returns how long the server asked us to wait before trying again, from the
+// Retry-After header (either a number of seconds or an HTTP date)
+func retryAfter(h http.Header) (wait time.Duration, ok bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if wait = time.Until(t); wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return
+}
+
+// Limiter decides when the client may send each request. It is called before every attempt
+// (including retries), and must be safe to use from multiple goroutines. *rate.Limiter from
+// golang.org/x/time/rate satisfies this interface.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is the default Limiter. It allows short bursts of requests, and then limits the
+// average to a fixed rate. A single bucket can be shared by several clients.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // the most tokens the bucket can hold
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a bucket that allows `rate` requests per second on average, and up to
+// `burst` requests at once. The bucket starts full.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available, or the context is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// take the token now, even if it hasn't been added yet, so waiting callers are served in order
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// give the token back for someone else
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Tokens returns the number of requests that can be sent now without waiting. It is negative if
+// callers are already waiting for tokens.
+func (b *TokenBucket) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tokens := b.tokens + time.Since(b.last).Seconds()*b.rate
+	if tokens > b.burst {
+		tokens = b.burst
+	}
+	return tokens
+}
+
+// RateLimitStats shows how much a client has been held back by one of its limiters, so you can
+// tell when a strategy is limited by the request rate.
+type RateLimitStats struct {
+	// Tokens is the number of requests the limiter would allow now without waiting. It is only
+	// known (TokensKnown) if the limiter has a Tokens method, like TokenBucket and rate.Limiter.
+	Tokens      float64
+	TokensKnown bool
+
+	Requests    int64         // attempts that went through the limiter, including retries
+	Waits       int64         // attempts that had to wait for the limiter
+	WaitTime    time.Duration // total time spent waiting
+	LongestWait time.Duration
+}
+
+// limiterStats collects the stats for one limiter
+type limiterStats struct {
+	mu    sync.Mutex
+	stats RateLimitStats
+}
+
+// record adds an attempt that waited for the limiter
+func (s *limiterStats) record(wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Requests++
+	if wait >= time.Millisecond {
+		s.stats.Waits++
+		s.stats.WaitTime += wait
+		if wait > s.stats.LongestWait {
+			s.stats.LongestWait = wait
+		}
+	}
+}
+
+// get returns the stats, with the tokens currently available in the limiter
+func (s *limiterStats) get(l Limiter) RateLimitStats {
+	s.mu.Lock()
+	stats := s.stats
+	s.mu.Unlock()
+
+	if t, ok := l.(interface{ Tokens() float64 }); ok {
+		stats.Tokens, stats.TokensKnown = t.Tokens(), true
+	}
+	return stats
+}
+
+// RateLimitStats returns how much requests to the private endpoints have been held back by Limiter
+// since the client was created.
+func (c *Client) RateLimitStats() RateLimitStats {
+	return c.limiterStats.get(c.Limiter)
+}
+
+// PublicRateLimitStats returns how much requests to the public endpoints have been held back by
+// PublicLimiter since the client was created.
+func (c *Client) PublicRateLimitStats() RateLimitStats {
+	return c.publicLimiterStats.get(c.PublicLimiter)
+}
@@ -0,0 +1,72 @@
+package coinbasetrade
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Do makes a request to an endpoint this package doesn't have a method for yet. The request is
+// signed, rate limited, retried, logged, and traced like any other. The path is relative to the
+// client's Path, and the body (if any) should be JSON:
+//
+//	data, _, err := client.Do(ctx, coinbasetrade.Get, "/portfolios", url.Values{"portfolio_type": {"DEFAULT"}}, nil)
+//
+// The response body has already been read, so it is returned as data. If the final response isn't
+// a success, the error contains an *APIError, as with the other methods.
+func (c *Client) Do(ctx context.Context, method Method, path string, query url.Values, body []byte) ([]byte, *http.Response, error) {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	return c.send(ctx, method, path, query, body)
+}
+
+// rawKeeper is implemented by the types that can hold the JSON they were decoded from
+type rawKeeper interface {
+	setRaw(json.RawMessage)
+}
+
+func (a *Account) setRaw(raw json.RawMessage) { a.Raw = raw }
+func (o *Order) setRaw(raw json.RawMessage)   { o.Raw = raw }
+func (f *Fill) setRaw(raw json.RawMessage)    { f.Raw = raw }
+func (p *Product) setRaw(raw json.RawMessage) { p.Raw = raw }
+
+// rawItem returns the `key` field of a response (or the whole response if key is empty), if
+// KeepRaw is set
+func (c *Client) rawItem(data []byte, key string) json.RawMessage {
+	if !c.KeepRaw {
+		return nil
+	}
+	if key == "" {
+		return append(json.RawMessage(nil), data...)
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+	return fields[key]
+}
+
+// keepRaw sets the Raw field of each item of a page to its JSON from the `key` field of the
+// response, if the client's KeepRaw is set
+func keepRaw[T any](c *Client, data []byte, key string, items []T) {
+	if !c.KeepRaw || len(items) == 0 {
+		return
+	}
+
+	var raws []json.RawMessage
+	if json.Unmarshal(c.rawItem(data, key), &raws) != nil {
+		return
+	}
+	for n := range items {
+		if r, ok := any(&items[n]).(rawKeeper); ok && n < len(raws) {
+			r.setRaw(raws[n])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package coinbasetrade
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Resample combines candles of the source granularity into candles covering a longer period, such
+// as 4 hours, 12 hours, or a week, which the api doesn't offer. Periods are aligned to whole
+// multiples of the period in UTC (weeks start on Monday). The open and close come from the first
+// and last candle in each period, the high and low are the extremes of all of them, and the volume
+// is the total.
+//
+// The first and last periods may only be partly covered by the candles provided. These are left out
+// unless includePartial is set. The result is in the same order as the candles provided (the
+// api returns newest first).
+func Resample(candles []Candle, source Granularity, period time.Duration, includePartial bool) (out []Candle) {
+	if len(candles) == 0 || period <= 0 {
+		return
+	}
+
+	sorted := make([]Candle, len(candles))
+	copy(sorted, candles)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartUnix < sorted[j].StartUnix })
+	newestFirst := candles[0].StartUnix > candles[len(candles)-1].StartUnix
+
+	// the range of time actually covered by the source candles
+	first := sorted[0].StartTime
+	last := sorted[len(sorted)-1].StartTime.Add(source.Duration())
+
+	for _, c := range sorted {
+		bucket := c.StartTime.UTC().Truncate(period)
+
+		if n := len(out); n > 0 && out[n-1].StartTime.Equal(bucket) {
+			b := &out[n-1]
+			if c.High.GreaterThan(b.High) {
+				b.High = c.High
+			}
+			if c.Low.LessThan(b.Low) {
+				b.Low = c.Low
+			}
+			b.Close = c.Close
+			b.Volume = b.Volume.Add(c.Volume)
+			continue
+		}
+
+		out = append(out, Candle{
+			StartString: strconv.FormatInt(bucket.Unix(), 10),
+			StartTime:   bucket,
+			StartUnix:   bucket.Unix(),
+			Low:         c.Low,
+			High:        c.High,
+			Open:        c.Open,
+			Close:       c.Close,
+			Volume:      c.Volume,
+		})
+	}
+
+	if !includePartial {
+		if out[0].StartTime.Before(first) {
+			out = out[1:]
+		}
+		if n := len(out); n > 0 && out[n-1].StartTime.Add(period).After(last) {
+			out = out[:n-1]
+		}
+	}
+
+	if newestFirst {
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package coinbasetrade
+
+import (
+	"net/http"
+	"time"
+)
+
+// requestIDHeaders are the response headers that can identify a request to Coinbase support, in
+// order of preference
+var requestIDHeaders = []string{"X-Request-Id", "X-Trace-Id", "Cf-Ray"}
+
+// requestID returns the id of a request from its response headers, if there is one
+func requestID(h http.Header) string {
+	for _, k := range requestIDHeaders {
+		if v := h.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ResponseInfo describes one attempt at a request. Set OnResponse in the ClientConfig to receive
+// one for every attempt, i.e. to log the request ids of slow or failed calls.
+type ResponseInfo struct {
+	Method     Method
+	Endpoint   string // the endpoint that was called, i.e. "/orders/historical/{order id}"
+	Attempt    int    // 1 for the first attempt, 2 for the first retry, etc
+	StatusCode int    // 0 if no response was received
+	RequestID  string // Coinbase's id for the request, if the response included one
+	Latency    time.Duration
+	Header     http.Header // the response headers, or nil if no response was received
+	Err        error       // the network error, if no response was received
+}
@@ -0,0 +1,96 @@
+package coinbasetrade
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail for transient reasons (network errors, rate
+// limiting, and server errors) are retried. Each retry waits twice as long as the one before,
+// plus some random jitter, up to MaxDelay.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts per request, including the first; 1 or less disables retries
+	MinDelay    time.Duration // wait before the first retry
+	MaxDelay    time.Duration // the longest wait between attempts
+
+	// Requests that change state (placing or cancelling orders) are sent as POST. A POST that
+	// timed out or got a server error may still have been acted on, so it is only retried when it
+	// was rate limited or never reached the server. Set this to never retry a POST.
+	SkipNonIdempotent bool
+}
+
+// DefaultRetryPolicy is used by clients that aren't given a policy of their own.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinDelay:    time.Millisecond * 500,
+	MaxDelay:    time.Second * 10,
+}
+
+// networkError marks failures to reach the server or read its response, which are worth retrying
+type networkError struct {
+	error
+}
+
+func (e networkError) Unwrap() error {
+	return e.error
+}
+
+// shouldRetry decides if a request is worth trying again, based on the outcome of the last attempt
+func (p RetryPolicy) shouldRetry(m Method, attempt int, res *http.Response, err error) bool {
+	if attempt >= p.MaxAttempts || (p.SkipNonIdempotent && m == Post) {
+		return false
+	}
+
+	if m == Post {
+		if err != nil {
+			return notSent(err)
+		}
+		return res.StatusCode == http.StatusTooManyRequests
+	}
+
+	if err != nil {
+		var ne networkError
+		return errors.As(err, &ne)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// notSent reports whether a request failed before it could reach the server, because the host
+// couldn't be resolved or connected to
+func notSent(err error) bool {
+	var ne networkError
+	if !errors.As(err, &ne) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// backoff returns how long to wait before the given retry (starting at 1)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.MinDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// wait somewhere between half and all of the delay, so clients that failed together don't
+	// all retry together
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
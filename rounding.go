@@ -0,0 +1,42 @@
+package coinbasetrade
+
+import "github.com/shopspring/decimal"
+
+// RoundingMode chooses which way a value is rounded to an increment.
+type RoundingMode int
+
+const (
+	RoundNearest RoundingMode = iota // halves are rounded away from zero
+	RoundDown
+	RoundUp
+)
+
+// RoundToIncrement rounds value to a whole multiple of increment. If increment isn't positive,
+// value is returned unchanged.
+func RoundToIncrement(value, increment decimal.Decimal, mode RoundingMode) decimal.Decimal {
+	if !increment.IsPositive() {
+		return value
+	}
+
+	steps := value.Div(increment)
+	switch mode {
+	case RoundDown:
+		steps = steps.Floor()
+	case RoundUp:
+		steps = steps.Ceil()
+	default:
+		steps = steps.Round(0)
+	}
+	return steps.Mul(increment)
+}
+
+// roundForProduct rounds the sizes in an order down to the product's increments, and the prices
+// to its price increment using the given mode
+func (oc OrderConfiguration) roundForProduct(p Product, priceMode RoundingMode) OrderConfiguration {
+	oc.BaseSize = RoundToIncrement(oc.BaseSize, p.BaseIncrement, RoundDown)
+	oc.QuoteSize = RoundToIncrement(oc.QuoteSize, p.QuoteIncrement, RoundDown)
+	oc.LimitPrice = RoundToIncrement(oc.LimitPrice, p.priceIncrement(), priceMode)
+	oc.StopPrice = RoundToIncrement(oc.StopPrice, p.priceIncrement(), priceMode)
+	oc.StopTriggerPrice = RoundToIncrement(oc.StopTriggerPrice, p.priceIncrement(), priceMode)
+	return oc
+}
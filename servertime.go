@@ -0,0 +1,62 @@
+package coinbasetrade
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// clockOffset is the difference between the server's clock and ours, added to the local time when
+// signing requests
+type clockOffset struct {
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+// GetServerTime returns the current time according to Coinbase. This endpoint doesn't require
+// authentication.
+func (c *Client) GetServerTime() (t time.Time, err error) {
+	res := struct {
+		EpochMillis int64 `json:"epochMillis,string"`
+	}{}
+
+	if _, err = c.makeRequest(context.Background(), Get, getServerTimeEndpoint, url.Values{}, []byte{}, &res, nil); err != nil {
+		return
+	}
+	t = time.UnixMilli(res.EpochMillis)
+	return
+}
+
+// SyncClock measures how far the local clock is from the server's, and from then on offsets the
+// timestamps used to sign requests by that amount. Use this if requests fail with "invalid
+// timestamp" errors because the local clock is wrong. The measured offset is returned; it is
+// positive if the local clock is behind.
+func (c *Client) SyncClock() (offset time.Duration, err error) {
+	start := time.Now()
+	var server time.Time
+	if server, err = c.GetServerTime(); err != nil {
+		return
+	}
+
+	// assume the server read its clock halfway through the round trip
+	elapsed := time.Since(start)
+	offset = server.Sub(start.Add(elapsed / 2)).Round(time.Millisecond)
+
+	c.clock.mu.Lock()
+	c.clock.offset = offset
+	c.clock.mu.Unlock()
+	return
+}
+
+// ClockOffset returns the offset measured by the last call to SyncClock.
+func (c *Client) ClockOffset() time.Duration {
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	return c.clock.offset
+}
+
+// now returns the current time, corrected by the measured clock offset
+func (c *Client) now() time.Time {
+	return time.Now().Add(c.ClockOffset())
+}
@@ -0,0 +1,171 @@
+package coinbasetrade
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Liquidity is the top of the book of one product.
+type Liquidity struct {
+	ProductID string
+	BestBid   BookLevel
+	BestAsk   BookLevel
+	SpreadBps decimal.Decimal // the spread in basis points of the mid-market price
+	Updated   time.Time
+	OK        bool // whether the spread and sizes are within the monitor's thresholds
+}
+
+// SpreadMonitor tracks the spread and the size at the best bid and ask of a set of products, and
+// calls OnChange when one moves outside of (or back within) the thresholds, e.g. to pause market
+// orders while liquidity is thin. It is fed by ticker messages passed to Apply, by an OrderBook
+// through Update, or by polling the order book while Run is going. It is safe to use from multiple
+// goroutines.
+type SpreadMonitor struct {
+	ProductIDs   []string
+	MaxSpreadBps decimal.Decimal // the widest acceptable spread; zero means any
+	MinTopSize   decimal.Decimal // the smallest acceptable size at the best bid and ask; zero means any
+
+	Interval time.Duration   // how often Run polls the order books (default 10 seconds)
+	OnChange func(Liquidity) // called when a product is first seen, and whenever OK changes
+	OnError  func(error)     // called when polling fails during Run
+
+	client    *Client
+	mu        sync.Mutex
+	liquidity map[string]Liquidity
+}
+
+// NewSpreadMonitor creates a SpreadMonitor for the given products, which uses this client to poll
+// their order books. Set the thresholds before feeding it.
+func (c *Client) NewSpreadMonitor(onChange func(Liquidity), productIDs ...string) *SpreadMonitor {
+	return &SpreadMonitor{
+		ProductIDs: productIDs,
+		Interval:   time.Second * 10,
+		OnChange:   onChange,
+		client:     c,
+		liquidity:  make(map[string]Liquidity),
+	}
+}
+
+// Subscribe subscribes the websocket to the ticker channel for the monitor's products.
+func (m *SpreadMonitor) Subscribe(ws *Websocket) error {
+	return ws.Subscribe(TickerChannel, m.ProductIDs...)
+}
+
+// Apply updates the monitor from a ticker or ticker_batch message. Other messages are ignored.
+func (m *SpreadMonitor) Apply(msg WebsocketMessage) error {
+	if msg.Channel != TickerChannel && msg.Channel != TickerBatchChannel {
+		return nil
+	}
+
+	events, err := msg.TickerEvents()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		for _, t := range e.Tickers {
+			m.Update(t.ProductID,
+				BookLevel{Price: t.BestBid, Size: t.BestBidQuantity},
+				BookLevel{Price: t.BestAsk, Size: t.BestAskQuantity},
+				msg.Timestamp)
+		}
+	}
+	return nil
+}
+
+// Update sets the best bid and ask of a product, e.g. from an OrderBook's BestBid and BestAsk.
+func (m *SpreadMonitor) Update(productID string, bid, ask BookLevel, at time.Time) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	l := Liquidity{ProductID: productID, BestBid: bid, BestAsk: ask, Updated: at}
+
+	mid := bid.Price.Add(ask.Price).Div(decimal.NewFromInt(2))
+	if mid.IsPositive() {
+		l.SpreadBps = ask.Price.Sub(bid.Price).Div(mid).Mul(decimal.NewFromInt(10000))
+	}
+	l.OK = m.within(l)
+
+	m.mu.Lock()
+	prev, seen := m.liquidity[productID]
+	m.liquidity[productID] = l
+	m.mu.Unlock()
+
+	if m.OnChange != nil && (!seen || prev.OK != l.OK) {
+		m.OnChange(l)
+	}
+}
+
+// within reports whether the top of the book is within the thresholds
+func (m *SpreadMonitor) within(l Liquidity) bool {
+	if !l.BestBid.Price.IsPositive() || !l.BestAsk.Price.IsPositive() {
+		return false // one side of the book is empty
+	}
+	if m.MaxSpreadBps.IsPositive() && l.SpreadBps.GreaterThan(m.MaxSpreadBps) {
+		return false
+	}
+	if m.MinTopSize.IsPositive() && (l.BestBid.Size.LessThan(m.MinTopSize) || l.BestAsk.Size.LessThan(m.MinTopSize)) {
+		return false
+	}
+	return true
+}
+
+// Poll fetches the top of the order book of every product now.
+func (m *SpreadMonitor) Poll() error {
+	for _, id := range m.ProductIDs {
+		book, err := m.client.GetProductBook(id, 1, decimal.Zero)
+		if err != nil {
+			return err
+		}
+
+		var bid, ask BookLevel
+		if len(book.Bids) > 0 {
+			bid = book.Bids[0]
+		}
+		if len(book.Asks) > 0 {
+			ask = book.Asks[0]
+		}
+		m.Update(id, bid, ask, book.Time)
+	}
+	return nil
+}
+
+// Run polls the order books straight away, then every Interval, until the context is done.
+func (m *SpreadMonitor) Run(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = time.Second * 10
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(); err != nil && m.OnError != nil {
+			m.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get returns the latest top of the book of a product, and false if it hasn't been seen.
+func (m *SpreadMonitor) Get(productID string) (l Liquidity, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok = m.liquidity[productID]
+	return
+}
+
+// OK reports whether a product's spread and sizes were within the thresholds when last seen. It is
+// false for a product that hasn't been seen.
+func (m *SpreadMonitor) OK(productID string) bool {
+	l, _ := m.Get(productID)
+	return l.OK
+}
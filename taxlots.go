@@ -0,0 +1,215 @@
+package coinbasetrade
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LotMethod chooses which lots are sold first when matching sells against buys.
+type LotMethod string
+
+const (
+	FIFO LotMethod = "FIFO" // first in, first out: the oldest lot is sold first
+	LIFO LotMethod = "LIFO" // last in, first out: the newest lot is sold first
+	HIFO LotMethod = "HIFO" // highest in, first out: the lot with the highest cost is sold first
+)
+
+// Disposal is part of a sell matched against one lot bought earlier. Cost basis, proceeds, and
+// gain are in the product's quote currency; buy fees are included in the cost basis, and sell fees
+// are taken off the proceeds.
+type Disposal struct {
+	ProductID     string
+	Currency      string // the currency sold
+	QuoteCurrency string // the currency of the cost basis, proceeds, and gain
+	Size          decimal.Decimal
+	Acquired      time.Time
+	Disposed      time.Time
+	CostBasis     decimal.Decimal
+	Proceeds      decimal.Decimal
+	Gain          decimal.Decimal
+	BuyTradeID    string
+	SellTradeID   string
+	Unmatched     bool // true if there were no lots left to match, so the cost basis is unknown (zero)
+}
+
+// lot is a quantity bought in one fill that hasn't been sold yet
+type lot struct {
+	unitCost  decimal.Decimal // price plus fees, per unit
+	time      time.Time
+	tradeID   string
+	remaining decimal.Decimal
+}
+
+// CostBasisReport matches the sells in fills against earlier buys using the given method, and
+// returns a disposal for each part of a sell that happened between start and end. The full fill
+// history should be passed in, since buys made before start can be sold within it; a zero start or
+// end leaves that side of the range open. Lots are kept separately for each product, since their
+// cost is in the product's quote currency.
+func CostBasisReport(fills []Fill, method LotMethod, start, end time.Time) (disposals []Disposal, err error) {
+	switch method {
+	case FIFO, LIFO, HIFO:
+	default:
+		err = fmt.Errorf("unknown lot method %q", method)
+		return
+	}
+
+	sorted := make([]Fill, len(fills))
+	copy(sorted, fills)
+	sortFills(sorted)
+
+	lots := make(map[string][]*lot)
+	for _, f := range sorted {
+		size := f.Size
+		if f.SizeInQuote && !f.Price.IsZero() {
+			size = size.Div(f.Price)
+		}
+		if !size.IsPositive() {
+			continue
+		}
+
+		if f.Side == Buy {
+			lots[f.ProductID] = append(lots[f.ProductID], &lot{
+				unitCost:  size.Mul(f.Price).Add(f.Commission).Div(size),
+				time:      f.TradeTime,
+				tradeID:   f.TradeID,
+				remaining: size,
+			})
+			continue
+		}
+
+		inRange := (start.IsZero() || !f.TradeTime.Before(start)) && (end.IsZero() || f.TradeTime.Before(end))
+		base, quote := splitProductID(f.ProductID)
+		unitProceeds := size.Mul(f.Price).Sub(f.Commission).Div(size)
+
+		left := size
+		for left.IsPositive() {
+			d := Disposal{
+				ProductID:     f.ProductID,
+				Currency:      base,
+				QuoteCurrency: quote,
+				Disposed:      f.TradeTime,
+				SellTradeID:   f.TradeID,
+			}
+
+			if l := nextLot(lots[f.ProductID], method); l != nil {
+				d.Size = decimal.Min(left, l.remaining)
+				d.Acquired = l.time
+				d.BuyTradeID = l.tradeID
+				d.CostBasis = d.Size.Mul(l.unitCost)
+				l.remaining = l.remaining.Sub(d.Size)
+			} else {
+				d.Size = left
+				d.Unmatched = true
+			}
+			left = left.Sub(d.Size)
+
+			d.Proceeds = d.Size.Mul(unitProceeds)
+			d.Gain = d.Proceeds.Sub(d.CostBasis)
+			if inRange {
+				disposals = append(disposals, d)
+			}
+		}
+
+		lots[f.ProductID] = openLots(lots[f.ProductID])
+	}
+	return
+}
+
+// nextLot returns the lot that should be sold next, or nil if there are none left
+func nextLot(lots []*lot, method LotMethod) (next *lot) {
+	for _, l := range lots {
+		if !l.remaining.IsPositive() {
+			continue
+		}
+		switch {
+		case next == nil:
+			next = l
+		case method == LIFO:
+			next = l // lots are in time order, so the last one is the newest
+		case method == HIFO && l.unitCost.GreaterThan(next.unitCost):
+			next = l
+		}
+	}
+	return
+}
+
+// openLots removes lots that have been completely sold
+func openLots(lots []*lot) []*lot {
+	open := lots[:0]
+	for _, l := range lots {
+		if l.remaining.IsPositive() {
+			open = append(open, l)
+		}
+	}
+	return open
+}
+
+// splitProductID returns the base and quote currencies of a product id like "BTC-USD"
+func splitProductID(id string) (base, quote string) {
+	parts := strings.SplitN(id, "-", 2)
+	base = parts[0]
+	if len(parts) > 1 {
+		quote = parts[1]
+	}
+	return
+}
+
+// CostBasisReport fetches the user's full fill history up to end, and matches their sells against
+// their buys. See CostBasisReport for details.
+func (c *Client) CostBasisReport(method LotMethod, start, end time.Time) (disposals []Disposal, err error) {
+	var fills []Fill
+	if fills, err = c.allFills(context.Background(), ListFillsParameters{EndSequenceTime: end}); err != nil {
+		return
+	}
+	return CostBasisReport(fills, method, start, end)
+}
+
+var disposalsCSVHeader = []string{"product_id", "currency", "quote_currency", "size", "acquired", "disposed", "cost_basis", "proceeds", "gain", "buy_trade_id", "sell_trade_id", "unmatched"}
+
+// WriteDisposalsCSV writes disposals to w as CSV, with a header row. Times are in UTC, and the
+// acquired time is left empty for unmatched disposals.
+func WriteDisposalsCSV(w io.Writer, disposals []Disposal) (err error) {
+	cw := csv.NewWriter(w)
+	if err = cw.Write(disposalsCSVHeader); err != nil {
+		return formatError("write disposals csv", err)
+	}
+
+	for _, d := range disposals {
+		acquired, unmatched := "", "false"
+		if !d.Acquired.IsZero() {
+			acquired = d.Acquired.UTC().Format(time.RFC3339Nano)
+		}
+		if d.Unmatched {
+			unmatched = "true"
+		}
+		row := []string{
+			d.ProductID,
+			d.Currency,
+			d.QuoteCurrency,
+			d.Size.String(),
+			acquired,
+			d.Disposed.UTC().Format(time.RFC3339Nano),
+			d.CostBasis.String(),
+			d.Proceeds.String(),
+			d.Gain.String(),
+			d.BuyTradeID,
+			d.SellTradeID,
+			unmatched,
+		}
+		if err = cw.Write(row); err != nil {
+			return formatError("write disposals csv", err)
+		}
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		err = formatError("write disposals csv", err)
+	}
+	return
+}
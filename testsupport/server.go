@@ -0,0 +1,447 @@
+// Package testsupport provides a mock Coinbase Advanced Trade server, so code that uses
+// coinbasetrade can be tested without hitting the real API.
+//
+//	srv := testsupport.NewServer()
+//	defer srv.Close()
+//	srv.Accounts = []coinbasetrade.Account{{ID: "acc-1", Currency: "USD"}}
+//
+//	client := srv.Client()
+//	accounts, err := client.ListAccounts(coinbasetrade.ListAccountsParameters{})
+//
+// The server serves the accounts, products, orders, fills, and key permissions in its fields,
+// records every request, and rejects requests that aren't signed correctly. Placed orders are
+// added to Orders as open orders, and cancelled orders are marked as cancelled. Use Handle to
+// return a canned response for any endpoint instead.
+package testsupport
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+)
+
+// The credentials that the server expects requests to be signed with, unless they are changed.
+const (
+	DefaultKey    = "test-key"
+	DefaultSecret = "test-secret"
+)
+
+const apiPath = "/api/v3/brokerage"
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string // without the /api/v3/brokerage prefix, i.e. "/orders"
+	Query  url.Values
+	Body   []byte
+}
+
+// response is a canned response set with Handle
+type response struct {
+	status int
+	body   []byte
+}
+
+// Server is a mock Advanced Trade server. Fields can be changed at any time; the server locks
+// itself while handling a request, so use Lock and Unlock around changes made while requests may
+// be in flight.
+type Server struct {
+	*httptest.Server
+
+	// requests must be signed with these HMAC credentials...
+	Key    string
+	Secret string
+
+	// ...or with a JWT for this CDP key, if PublicKey is set
+	KeyName   string
+	PublicKey *ecdsa.PublicKey
+
+	// data served by the default handlers
+	Accounts []coinbasetrade.Account
+	Products []coinbasetrade.Product
+	Orders   []coinbasetrade.Order
+	Fills    []coinbasetrade.Fill
+
+	// returned by /key_permissions; everything is allowed by default
+	Permissions coinbasetrade.KeyPermissions
+
+	mu       sync.Mutex
+	handlers map[string]response // keyed by method and path, i.e. "GET /accounts"
+	requests []Request
+	nextID   int
+}
+
+// NewServer starts a mock server that expects requests signed with DefaultKey and DefaultSecret.
+// Call Close when done with it.
+func NewServer() *Server {
+	s := &Server{
+		Key:      DefaultKey,
+		Secret:   DefaultSecret,
+		handlers: make(map[string]response),
+
+		Permissions: coinbasetrade.KeyPermissions{CanView: true, CanTrade: true, CanTransfer: true, PortfolioType: "DEFAULT"},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Lock locks the server, so its fields can be changed safely while requests may be in flight.
+func (s *Server) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock unlocks the server.
+func (s *Server) Unlock() {
+	s.mu.Unlock()
+}
+
+// Config returns a client config for the server, with its credentials and without retries.
+func (s *Server) Config() *coinbasetrade.ClientConfig {
+	return &coinbasetrade.ClientConfig{
+		Host:   s.URL,
+		Path:   apiPath,
+		Key:    s.Key,
+		Secret: s.Secret,
+		Retry:  &coinbasetrade.RetryPolicy{MaxAttempts: 1},
+	}
+}
+
+// Client returns a client for the server.
+func (s *Server) Client() *coinbasetrade.Client {
+	return coinbasetrade.NewClient(s.Config())
+}
+
+// Handle sets a canned response for requests with the given method and path (without the
+// /api/v3/brokerage prefix, i.e. "/orders/historical/batch"), replacing the default handler. The
+// body is sent as is if it is a string or []byte, and encoded as JSON otherwise.
+func (s *Server) Handle(method, path string, status int, body interface{}) {
+	var data []byte
+	switch b := body.(type) {
+	case string:
+		data = []byte(b)
+	case []byte:
+		data = b
+	default:
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			panic(fmt.Sprintf("testsupport: can't encode response for %s %s: %s", method, path, err))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method+" "+path] = response{status, data}
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.Path, apiPath)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: path, Query: r.URL.Query(), Body: body})
+
+	// the public market endpoints and the server time don't need to be signed
+	if !strings.HasPrefix(path, "/market/") && path != "/time" {
+		if err := s.authenticate(r, body); err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "UNAUTHORIZED", "message": err.Error()})
+			return
+		}
+	}
+
+	if h, ok := s.handlers[r.Method+" "+path]; ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(h.status)
+		w.Write(h.body)
+		return
+	}
+
+	status, res := s.route(r.Method, strings.TrimPrefix(path, "/market"), r.URL.Query(), body)
+	writeJSON(w, status, res)
+}
+
+// route handles a request with the default handlers
+func (s *Server) route(method, path string, query url.Values, body []byte) (int, interface{}) {
+	notFound := map[string]string{"error": "NOT_FOUND", "message": "not found"}
+
+	switch {
+	case method == http.MethodGet && path == "/time":
+		now := time.Now()
+		return http.StatusOK, map[string]string{
+			"iso":          now.UTC().Format(time.RFC3339Nano),
+			"epochSeconds": strconv.FormatInt(now.Unix(), 10),
+			"epochMillis":  strconv.FormatInt(now.UnixMilli(), 10),
+		}
+
+	case method == http.MethodGet && path == "/key_permissions":
+		return http.StatusOK, s.Permissions
+
+	case method == http.MethodGet && path == "/accounts":
+		accounts := s.Accounts
+		if portfolio := query.Get("retail_portfolio_id"); portfolio != "" {
+			accounts = nil
+			for _, a := range s.Accounts {
+				if a.RetailPortfolioID == portfolio {
+					accounts = append(accounts, a)
+				}
+			}
+		}
+		return http.StatusOK, map[string]interface{}{"accounts": orEmpty(accounts), "has_next": false, "size": len(accounts)}
+
+	case method == http.MethodGet && strings.HasPrefix(path, "/accounts/"):
+		for _, a := range s.Accounts {
+			if a.ID == strings.TrimPrefix(path, "/accounts/") {
+				return http.StatusOK, map[string]interface{}{"account": a}
+			}
+		}
+		return http.StatusNotFound, notFound
+
+	case method == http.MethodGet && path == "/products":
+		return http.StatusOK, map[string]interface{}{"products": orEmpty(s.Products), "num_products": len(s.Products)}
+
+	case method == http.MethodGet && strings.HasPrefix(path, "/products/") && strings.Count(path, "/") == 2:
+		for _, p := range s.Products {
+			if p.ID == strings.TrimPrefix(path, "/products/") {
+				return http.StatusOK, p
+			}
+		}
+		return http.StatusNotFound, notFound
+
+	case method == http.MethodGet && path == "/orders/historical/batch":
+		return http.StatusOK, map[string]interface{}{"orders": orEmpty(s.filterOrders(query)), "has_next": false}
+
+	case method == http.MethodGet && path == "/orders/historical/fills":
+		return http.StatusOK, map[string]interface{}{"fills": orEmpty(s.filterFills(query)), "has_next": false}
+
+	case method == http.MethodGet && strings.HasPrefix(path, "/orders/historical/"):
+		for _, o := range s.Orders {
+			if o.ID == strings.TrimPrefix(path, "/orders/historical/") {
+				return http.StatusOK, map[string]interface{}{"order": o}
+			}
+		}
+		return http.StatusNotFound, notFound
+
+	case method == http.MethodPost && path == "/orders":
+		return s.createOrder(body)
+
+	case method == http.MethodPost && path == "/orders/batch_cancel":
+		return s.cancelOrders(body)
+	}
+
+	return http.StatusNotFound, notFound
+}
+
+// createOrder adds a placed order to Orders as an open order
+func (s *Server) createOrder(body []byte) (int, interface{}) {
+	req := struct {
+		ClientOrderID      string                           `json:"client_order_id"`
+		ProductID          string                           `json:"product_id"`
+		Side               coinbasetrade.Side               `json:"side"`
+		OrderConfiguration coinbasetrade.OrderConfiguration `json:"order_configuration"`
+	}{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "INVALID_ARGUMENT", "message": err.Error()}
+	}
+
+	// as on Coinbase, an order with a client order id that has already been used isn't placed,
+	// and the existing order is returned
+	var order *coinbasetrade.Order
+	for i := range s.Orders {
+		if s.Orders[i].ClientOrderID == req.ClientOrderID {
+			order = &s.Orders[i]
+		}
+	}
+	if order == nil {
+		s.nextID++
+		s.Orders = append(s.Orders, coinbasetrade.Order{
+			ID:                 fmt.Sprintf("order-%d", s.nextID),
+			Product:            req.ProductID,
+			ClientOrderID:      req.ClientOrderID,
+			Side:               req.Side,
+			OrderConfiguration: req.OrderConfiguration,
+			Status:             coinbasetrade.Open,
+			CreatedTime:        time.Now(),
+		})
+		order = &s.Orders[len(s.Orders)-1]
+	}
+
+	return http.StatusOK, map[string]interface{}{
+		"success":  true,
+		"order_id": order.ID,
+		"success_response": map[string]interface{}{
+			"order_id":        order.ID,
+			"product_id":      order.Product,
+			"side":            order.Side,
+			"client_order_id": order.ClientOrderID,
+		},
+		"order_configuration": order.OrderConfiguration,
+	}
+}
+
+// cancelOrders marks open orders as cancelled
+func (s *Server) cancelOrders(body []byte) (int, interface{}) {
+	req := struct {
+		OrderIDs []string `json:"order_ids"`
+	}{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "INVALID_ARGUMENT", "message": err.Error()}
+	}
+
+	results := []map[string]interface{}{}
+	for _, id := range req.OrderIDs {
+		result := map[string]interface{}{"order_id": id, "success": false, "failure_reason": coinbasetrade.UnknownCancelOrder}
+		for i := range s.Orders {
+			if s.Orders[i].ID != id {
+				continue
+			}
+			if s.Orders[i].Status == coinbasetrade.Open {
+				s.Orders[i].Status = coinbasetrade.Cancelled
+				result["success"], result["failure_reason"] = true, coinbasetrade.UnknownCancelFailureReason
+			} else {
+				result["failure_reason"] = coinbasetrade.InvalidCancelRequest
+			}
+		}
+		results = append(results, result)
+	}
+	return http.StatusOK, map[string]interface{}{"results": results}
+}
+
+// filterOrders returns the orders matching the product and status filters of a list request
+func (s *Server) filterOrders(query url.Values) (orders []coinbasetrade.Order) {
+	for _, o := range s.Orders {
+		if p := query.Get("product_id"); p != "" && o.Product != p {
+			continue
+		}
+		if statuses := query["order_status"]; len(statuses) > 0 && !contains(statuses, string(o.Status)) {
+			continue
+		}
+		orders = append(orders, o)
+	}
+	return
+}
+
+// filterFills returns the fills matching the order and product filters of a list request
+func (s *Server) filterFills(query url.Values) (fills []coinbasetrade.Fill) {
+	for _, f := range s.Fills {
+		if id := query.Get("order_id"); id != "" && f.OrderID != id {
+			continue
+		}
+		if p := query.Get("product_id"); p != "" && f.ProductID != p {
+			continue
+		}
+		fills = append(fills, f)
+	}
+	return
+}
+
+// authenticate checks the signature of a request
+func (s *Server) authenticate(r *http.Request, body []byte) error {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return s.verifyJWT(strings.TrimPrefix(auth, "Bearer "), r.Method+" "+r.Host+r.URL.Path)
+	}
+
+	if r.Header.Get("CB-ACCESS-KEY") != s.Key {
+		return fmt.Errorf("unknown api key %q", r.Header.Get("CB-ACCESS-KEY"))
+	}
+
+	timestamp := r.Header.Get("CB-ACCESS-TIMESTAMP")
+	hash := hmac.New(sha256.New, []byte(s.Secret))
+	hash.Write([]byte(timestamp + r.Method + r.URL.Path + string(body)))
+	if !hmac.Equal([]byte(r.Header.Get("CB-ACCESS-SIGN")), []byte(hex.EncodeToString(hash.Sum(nil)))) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// verifyJWT checks a CDP key's token against PublicKey, and checks that it is for this request
+func (s *Server) verifyJWT(token, uri string) error {
+	if s.PublicKey == nil {
+		return fmt.Errorf("jwt received, but no public key is set")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("malformed jwt")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 64 {
+		return fmt.Errorf("malformed jwt signature")
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(s.PublicKey, hash[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+		return fmt.Errorf("invalid jwt signature")
+	}
+
+	var claims struct {
+		Sub string `json:"sub"`
+		Nbf int64  `json:"nbf"`
+		Exp int64  `json:"exp"`
+		URI string `json:"uri"`
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err == nil {
+		err = json.Unmarshal(data, &claims)
+	}
+	if err != nil {
+		return fmt.Errorf("malformed jwt claims")
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case s.KeyName != "" && claims.Sub != s.KeyName:
+		return fmt.Errorf("unknown api key name %q", claims.Sub)
+	case now < claims.Nbf-5 || now > claims.Exp+5: // allow a little clock drift
+		return fmt.Errorf("jwt has expired or isn't valid yet")
+	case claims.URI != uri:
+		return fmt.Errorf("jwt is for %q, not %q", claims.URI, uri)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// orEmpty makes sure a nil slice is sent as an empty array rather than null
+func orEmpty[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package testsupport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Interaction is a request and the response it got, as stored in a cassette. Request headers
+// aren't stored, since they hold the credentials and signature, so replaying doesn't need them.
+type Interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"` // the path and query, without the host
+	Body   string `json:"body,omitempty"`
+
+	Status         int                 `json:"status"`
+	ResponseHeader map[string][]string `json:"response_header,omitempty"`
+	ResponseBody   string              `json:"response_body"`
+}
+
+// VCR is an http.RoundTripper that records real API requests and responses to a cassette file,
+// and replays them later, so tests don't need live credentials. Set it as the Transport in a
+// ClientConfig:
+//
+//	vcr := testsupport.NewRecorder("testdata/orders.json", nil) // once, with real credentials
+//	vcr, err := testsupport.NewReplayer("testdata/orders.json") // in tests
+//
+//	client := coinbasetrade.NewClient(&coinbasetrade.ClientConfig{Transport: vcr, ...})
+//
+// When replaying, each request gets the first unused interaction with the same method and URL
+// (and body, if MatchBody is set), and a request with no match fails. Bodies aren't matched by
+// default, since they usually contain a generated client order id.
+type VCR struct {
+	MatchBody bool
+
+	// Redact is called for each interaction before it is stored, to remove anything private
+	// from the bodies (account ids, balances, etc). Headers that can hold secrets are always
+	// removed.
+	Redact func(*Interaction)
+
+	path      string
+	recording bool
+	next      http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// secretHeaders are response headers that are never stored
+var secretHeaders = []string{"Set-Cookie", "Authorization", "Cb-Access-Key", "Cb-Access-Sign"}
+
+// NewRecorder creates a VCR that sends requests on using next (http.DefaultTransport if nil),
+// and records them. Call Save to write the cassette to path.
+func NewRecorder(path string, next http.RoundTripper) *VCR {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &VCR{path: path, recording: true, next: next}
+}
+
+// NewReplayer creates a VCR that answers requests from the cassette at path.
+func NewReplayer(path string) (*VCR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+
+	v := &VCR{path: path}
+	if err = json.Unmarshal(data, &v.interactions); err != nil {
+		return nil, fmt.Errorf("decode cassette: %w", err)
+	}
+	v.used = make([]bool, len(v.interactions))
+	return v, nil
+}
+
+// RoundTrip records or replays a request.
+func (v *VCR) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if v.recording {
+		return v.record(req, body)
+	}
+	return v.replay(req, body)
+}
+
+// record sends a request on and stores the result
+func (v *VCR) record(req *http.Request, body []byte) (*http.Response, error) {
+	res, err := v.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// the client asks for gzip itself, so the body may still be compressed. It is stored (and
+	// passed on) decompressed, so the cassette holds readable text that Redact can work on.
+	if strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+		if resBody, err = gunzip(resBody); err != nil {
+			return nil, fmt.Errorf("testsupport: decompress response: %w", err)
+		}
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = int64(len(resBody))
+		res.Uncompressed = true
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	i := Interaction{
+		Method:         req.Method,
+		URL:            req.URL.RequestURI(),
+		Body:           string(body),
+		Status:         res.StatusCode,
+		ResponseHeader: res.Header.Clone(),
+		ResponseBody:   string(resBody),
+	}
+	for _, h := range secretHeaders {
+		delete(i.ResponseHeader, h)
+	}
+	if v.Redact != nil {
+		v.Redact(&i)
+	}
+
+	v.mu.Lock()
+	v.interactions = append(v.interactions, i)
+	v.used = append(v.used, true)
+	v.mu.Unlock()
+	return res, nil
+}
+
+// gunzip decompresses a gzipped body
+func gunzip(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// replay answers a request from the cassette
+func (v *VCR) replay(req *http.Request, body []byte) (*http.Response, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	uri := req.URL.RequestURI()
+	for n, i := range v.interactions {
+		if v.used[n] || i.Method != req.Method || i.URL != uri || (v.MatchBody && i.Body != string(body)) {
+			continue
+		}
+		v.used[n] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+			StatusCode:    i.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header(i.ResponseHeader).Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(i.ResponseBody))),
+			ContentLength: int64(len(i.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("testsupport: no recorded response for %s %s", req.Method, uri)
+}
+
+// Save writes the recorded interactions to the cassette file.
+func (v *VCR) Save() error {
+	v.mu.Lock()
+	data, err := json.MarshalIndent(v.interactions, "", "  ")
+	v.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+	if err = os.WriteFile(v.path, data, 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// Unused returns the recorded interactions that haven't been replayed, so a test can check that
+// every expected request was made.
+func (v *VCR) Unused() (unused []Interaction) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for n, i := range v.interactions {
+		if !v.used[n] {
+			unused = append(unused, i)
+		}
+	}
+	return
+}
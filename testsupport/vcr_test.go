@@ -0,0 +1,72 @@
+package testsupport_test
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+	"github.com/jmacwhyte/go-coinbase-trade/testsupport"
+)
+
+// TestVCRGzip records a gzipped response and replays it.
+func TestVCRGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"iso":"2024-01-02T03:04:05Z","epochSeconds":"1704164645","epochMillis":"1704164645000"}`))
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	config := func(transport http.RoundTripper) *coinbasetrade.ClientConfig {
+		return &coinbasetrade.ClientConfig{
+			Host:      srv.URL,
+			Path:      "/api/v3/brokerage",
+			Key:       testsupport.DefaultKey,
+			Secret:    testsupport.DefaultSecret,
+			Retry:     &coinbasetrade.RetryPolicy{MaxAttempts: 1},
+			Transport: transport,
+		}
+	}
+
+	cassette := filepath.Join(t.TempDir(), "time.json")
+	recorder := testsupport.NewRecorder(cassette, nil)
+	recorder.Redact = func(i *testsupport.Interaction) {
+		if !strings.Contains(i.ResponseBody, "epochMillis") {
+			t.Errorf("Redact was given an unreadable body: %q", i.ResponseBody)
+		}
+	}
+	recorded, err := coinbasetrade.NewClient(config(recorder)).GetServerTime()
+	if err != nil {
+		t.Fatalf("recording: %s", err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "Content-Encoding") {
+		t.Error("cassette still has the Content-Encoding header")
+	}
+
+	replayer, err := testsupport.NewReplayer(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := coinbasetrade.NewClient(config(replayer)).GetServerTime()
+	if err != nil {
+		t.Fatalf("replaying: %s", err)
+	}
+	if !replayed.Equal(recorded) {
+		t.Errorf("replayed time %s, recorded %s", replayed, recorded)
+	}
+}
@@ -0,0 +1,18 @@
+package coinbasetrade
+
+import "context"
+
+// Tracer creates spans around API requests and websocket messages, so they show up in distributed
+// traces. Set one as the Tracer of a ClientConfig; the oteltracing module has one for
+// OpenTelemetry. Endpoints are given as patterns, as with Metrics.
+type Tracer interface {
+	// StartRequest is called before a request waits for the limiter. The returned context is
+	// used for the request, and the returned function is called when it finishes, with the
+	// status code of the last attempt (0 if there was no response), the number of attempts, and
+	// the error returned to the caller.
+	StartRequest(ctx context.Context, method Method, endpoint string) (context.Context, func(status, attempts int, err error))
+
+	// StartWebsocketMessage is called when a websocket message is received, and the returned
+	// function when it has been handed to the reader of Messages.
+	StartWebsocketMessage(msg WebsocketMessage) func()
+}
@@ -0,0 +1,176 @@
+package coinbasetrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TWAP executes a large order as a series of smaller ones spread evenly over a period of time, to
+// get close to the time weighted average price. Each child order is an "immediate or cancel"
+// order: a market order, or a limit order if LimitPrice is set. Anything a child order doesn't
+// fill is added to the ones after it. It is safe to use from multiple goroutines.
+type TWAP struct {
+	ProductID  string
+	Side       Side
+	Size       decimal.Decimal // total size, in the base currency
+	Duration   time.Duration   // the time to spread the orders over
+	Slices     int             // the number of child orders
+	LimitPrice decimal.Decimal // optional, the worst price to accept
+	OnChild    func(Order)     // optional, called with the final state of each child order
+
+	client    *Client
+	mu        sync.Mutex
+	executed  decimal.Decimal
+	children  []Order
+	paused    bool
+	cancel    chan struct{}
+	cancelled sync.Once
+}
+
+// ErrTWAPIncomplete is returned by TWAP.Run when the last slice has passed and part of the size
+// still hasn't been executed, e.g. because the execution was paused, or child orders weren't filled.
+var ErrTWAPIncomplete = errors.New("twap ended without executing its full size")
+
+// TWAPStatus is the progress of a TWAP execution.
+type TWAPStatus struct {
+	Executed  decimal.Decimal
+	Remaining decimal.Decimal
+	Children  []Order
+	Paused    bool
+}
+
+// NewTWAP creates a TWAP execution of size over duration, in the given number of slices. Nothing
+// happens until Run is called.
+func (c *Client) NewTWAP(productID string, side Side, size decimal.Decimal, duration time.Duration, slices int) *TWAP {
+	return &TWAP{
+		ProductID: productID,
+		Side:      side,
+		Size:      size,
+		Duration:  duration,
+		Slices:    slices,
+		client:    c,
+		cancel:    make(chan struct{}),
+	}
+}
+
+// Run places the child orders on schedule, returning once the last one is done, or the execution
+// is cancelled, or the context is done. The first child is placed straight away. Child sizes are
+// rounded down to the product's base increment, and a slice is skipped (adding its size to the
+// next) if it would be smaller than the product's minimum size. If any of the size is left once
+// the last slice has passed, Run returns ErrTWAPIncomplete; Status shows how much.
+func (t *TWAP) Run(ctx context.Context) (err error) {
+	if t.Slices <= 0 {
+		return errors.New("twap must have at least one slice")
+	}
+
+	var product Product
+	if product, err = t.client.GetProduct(t.ProductID); err != nil {
+		return
+	}
+
+	interval := t.Duration / time.Duration(t.Slices)
+	start := time.Now()
+	for i := 0; i < t.Slices; i++ {
+		if i > 0 {
+			timer := time.NewTimer(time.Until(start.Add(interval * time.Duration(i))))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-t.cancel:
+				timer.Stop()
+				return
+			}
+		}
+
+		t.mu.Lock()
+		paused := t.paused
+		remaining := t.Size.Sub(t.executed)
+		t.mu.Unlock()
+		if paused {
+			continue
+		}
+		if !remaining.IsPositive() {
+			return
+		}
+
+		// spread what's left over the slices that are left
+		size := remaining.Div(decimal.NewFromInt(int64(t.Slices - i)))
+		if i == t.Slices-1 {
+			size = remaining
+		}
+		size = RoundToIncrement(size, product.BaseIncrement, RoundDown)
+		if !size.IsPositive() || size.LessThan(product.BaseMinSize) {
+			continue
+		}
+
+		var child Order
+		if t.LimitPrice.IsPositive() {
+			child, _, err = t.client.PlaceLimitIOC("", t.ProductID, t.Side, size, t.LimitPrice)
+		} else {
+			child, _, err = t.client.PlaceMarketIOCBase("", t.ProductID, t.Side, size)
+		}
+		if err != nil {
+			return
+		}
+		if err = child.Wait(ctx, t.client); err != nil {
+			return
+		}
+
+		t.mu.Lock()
+		t.executed = t.executed.Add(child.FilledSize)
+		t.children = append(t.children, child)
+		t.mu.Unlock()
+
+		if t.OnChild != nil {
+			t.OnChild(child)
+		}
+	}
+
+	t.mu.Lock()
+	remaining := t.Size.Sub(t.executed)
+	t.mu.Unlock()
+	if RoundToIncrement(remaining, product.BaseIncrement, RoundDown).IsPositive() {
+		err = fmt.Errorf("%w: %s of %s not executed", ErrTWAPIncomplete, remaining, t.Size)
+	}
+	return
+}
+
+// Pause stops new child orders from being placed until Resume is called. Slices that come up
+// while paused are skipped, and their size is spread over the slices after them. If the last
+// slice comes up while paused, what is left isn't executed, and Run returns ErrTWAPIncomplete.
+func (t *TWAP) Pause() {
+	t.mu.Lock()
+	t.paused = true
+	t.mu.Unlock()
+}
+
+// Resume continues placing child orders after Pause.
+func (t *TWAP) Resume() {
+	t.mu.Lock()
+	t.paused = false
+	t.mu.Unlock()
+}
+
+// Cancel stops the execution; Run returns once any child order in progress is done.
+func (t *TWAP) Cancel() {
+	t.cancelled.Do(func() { close(t.cancel) })
+}
+
+// Status returns the progress of the execution so far.
+func (t *TWAP) Status() TWAPStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return TWAPStatus{
+		Executed:  t.executed,
+		Remaining: t.Size.Sub(t.executed),
+		Children:  append([]Order(nil), t.children...),
+		Paused:    t.paused,
+	}
+}
@@ -0,0 +1,121 @@
+package coinbasetrade
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// productCacheTTL is how long product details are used for validation before being fetched again
+const productCacheTTL = time.Hour
+
+// ErrInvalidOrder is matched by every *ValidationError.
+var ErrInvalidOrder = errors.New("invalid order")
+
+// ValidationError describes an order setting that would be rejected by the server, found before
+// the order was sent.
+type ValidationError struct {
+	Field  string // e.g. "base_size"
+	Value  decimal.Decimal
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s %s %s", e.Field, e.Value, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidOrder
+}
+
+// productCache keeps recently fetched products, for validating orders
+type productCache struct {
+	mu       sync.Mutex
+	products map[string]cachedProduct
+}
+
+type cachedProduct struct {
+	product Product
+	fetched time.Time
+}
+
+// cachedProduct returns a product's details, fetching them if they aren't cached or are too old
+func (c *Client) cachedProduct(id string) (p Product, err error) {
+	c.products.mu.Lock()
+	cached, ok := c.products.products[id]
+	c.products.mu.Unlock()
+	if ok && time.Since(cached.fetched) < productCacheTTL {
+		return cached.product, nil
+	}
+
+	if p, err = c.GetProduct(id); err != nil {
+		return
+	}
+
+	c.products.mu.Lock()
+	if c.products.products == nil {
+		c.products.products = make(map[string]cachedProduct)
+	}
+	c.products.products[id] = cachedProduct{p, time.Now()}
+	c.products.mu.Unlock()
+	return
+}
+
+// validateForProduct checks the sizes and prices of an order against the product's increments and
+// limits
+func (oc OrderConfiguration) validateForProduct(p Product) error {
+	checks := []struct {
+		field    string
+		value    decimal.Decimal
+		inc      decimal.Decimal
+		min, max decimal.Decimal
+	}{
+		{"base_size", oc.BaseSize, p.BaseIncrement, p.BaseMinSize, p.BaseMaxSize},
+		{"quote_size", oc.QuoteSize, p.QuoteIncrement, p.QuoteMinSize, p.QuoteMaxSize},
+		{"limit_price", oc.LimitPrice, p.priceIncrement(), decimal.Zero, decimal.Zero},
+		{"stop_price", oc.StopPrice, p.priceIncrement(), decimal.Zero, decimal.Zero},
+		{"stop_trigger_price", oc.StopTriggerPrice, p.priceIncrement(), decimal.Zero, decimal.Zero},
+	}
+
+	for _, v := range checks {
+		switch {
+		case v.value.IsZero():
+			continue
+		case v.value.IsNegative():
+			return &ValidationError{v.field, v.value, "is negative"}
+		case v.inc.IsPositive() && !v.value.Mod(v.inc).IsZero():
+			return &ValidationError{v.field, v.value, fmt.Sprintf("is not a multiple of %s for %s", v.inc, p.ID)}
+		case v.min.IsPositive() && v.value.LessThan(v.min):
+			return &ValidationError{v.field, v.value, fmt.Sprintf("is less than the minimum of %s for %s", v.min, p.ID)}
+		case v.max.IsPositive() && v.value.GreaterThan(v.max):
+			return &ValidationError{v.field, v.value, fmt.Sprintf("is more than the maximum of %s for %s", v.max, p.ID)}
+		}
+	}
+	return nil
+}
+
+// prepareOrder rounds an order to the product's increments and validates it before it is sent,
+// depending on how the client is set up
+func (c *Client) prepareOrder(productID string, oc OrderConfiguration) (_ OrderConfiguration, err error) {
+	if err = oc.validate(); err != nil || (!c.ValidateOrders && !c.RoundOrders) {
+		return oc, err
+	}
+
+	var p Product
+	if p, err = c.cachedProduct(productID); err != nil {
+		return oc, err
+	}
+	if c.RoundOrders {
+		oc = oc.roundForProduct(p, c.PriceRounding)
+	}
+	if c.ValidateOrders {
+		err = oc.validateForProduct(p)
+	}
+	return oc, err
+}
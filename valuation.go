@@ -0,0 +1,85 @@
+package coinbasetrade
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// AssetValue is the value of the user's balance in one currency.
+type AssetValue struct {
+	Currency string
+	Balance  decimal.Decimal // available plus held
+	Price    decimal.Decimal // the price of one unit, in the quote currency
+	Value    decimal.Decimal
+	Priced   bool // false if no product could be found to price the currency, in which case Value is zero
+}
+
+// PortfolioValue is the total value of the user's accounts in one quote currency.
+type PortfolioValue struct {
+	Quote  string
+	Total  decimal.Decimal
+	Assets []AssetValue // sorted by value, highest first
+}
+
+// GetPortfolioValue fetches every account and the spot price of every product, and values the
+// balance of each currency in quote (e.g. "USD"). Each currency is priced with its product against
+// quote, or the inverse of the quote's product against it if that is all there is. Currencies that
+// can't be priced either way are included with Priced set to false, and don't count towards the
+// total.
+func (c *Client) GetPortfolioValue(quote string) (v PortfolioValue, err error) {
+	var accounts []Account
+	if accounts, err = c.allAccounts(); err != nil {
+		return
+	}
+	balances := make(map[string]decimal.Decimal)
+	for _, a := range accounts {
+		balances[a.Currency] = balances[a.Currency].Add(a.AvailableBalance.Value).Add(a.HoldBalance.Value)
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	var pl ProductList
+	if pl, err = c.ListProducts(ListProductsParameters{Type: ProductTypeSpot}); err != nil {
+		return
+	}
+	for pl.Next() {
+		for _, p := range pl.Products {
+			prices[p.ID] = p.Price
+		}
+		if err = pl.NextPage(); err != nil {
+			return
+		}
+	}
+
+	v.Quote = quote
+	for currency, balance := range balances {
+		if balance.IsZero() {
+			continue
+		}
+
+		a := AssetValue{Currency: currency, Balance: balance}
+		direct, inverse := prices[currency+"-"+quote], prices[quote+"-"+currency]
+		switch {
+		case currency == quote:
+			a.Price, a.Priced = decimal.NewFromInt(1), true
+		case direct.IsPositive():
+			a.Price, a.Priced = direct, true
+		case inverse.IsPositive():
+			a.Price, a.Priced = decimal.NewFromInt(1).Div(inverse), true
+		}
+
+		if a.Priced {
+			a.Value = balance.Mul(a.Price)
+			v.Total = v.Total.Add(a.Value)
+		}
+		v.Assets = append(v.Assets, a)
+	}
+
+	sort.Slice(v.Assets, func(i, j int) bool {
+		if !v.Assets[i].Value.Equal(v.Assets[j].Value) {
+			return v.Assets[i].Value.GreaterThan(v.Assets[j].Value)
+		}
+		return v.Assets[i].Currency < v.Assets[j].Currency
+	})
+	return
+}
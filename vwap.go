@@ -0,0 +1,104 @@
+package coinbasetrade
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// VWAP calculates the volume weighted average price of trades or fills over a rolling window. It
+// is safe to use from multiple goroutines.
+type VWAP struct {
+	Window time.Duration // only trades this close to the newest one are included; 0 includes everything
+
+	mu      sync.Mutex
+	entries []vwapEntry
+	value   decimal.Decimal // sum of price * size
+	volume  decimal.Decimal // sum of size
+	newest  time.Time
+}
+
+type vwapEntry struct {
+	time  time.Time
+	value decimal.Decimal
+	size  decimal.Decimal
+}
+
+// NewVWAP creates a VWAP over the given window. The window is measured back from the time of the
+// newest trade added rather than the current time, so historical data gives the same result as
+// live data.
+func NewVWAP(window time.Duration) *VWAP {
+	return &VWAP{Window: window}
+}
+
+// Add includes a trade of `size` at `price`, made at time t.
+func (v *VWAP) Add(t time.Time, price, size decimal.Decimal) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	e := vwapEntry{t, price.Mul(size), size}
+	v.value = v.value.Add(e.value)
+	v.volume = v.volume.Add(e.size)
+
+	// keep the entries in time order, so the oldest can be removed from the front
+	i := len(v.entries)
+	for i > 0 && v.entries[i-1].time.After(t) {
+		i--
+	}
+	v.entries = append(v.entries, vwapEntry{})
+	copy(v.entries[i+1:], v.entries[i:])
+	v.entries[i] = e
+
+	if t.After(v.newest) {
+		v.newest = t
+	}
+	v.evict()
+}
+
+// AddTrade includes a trade from the market.
+func (v *VWAP) AddTrade(t Trade) {
+	v.Add(t.Time, t.Price, t.Size)
+}
+
+// AddFill includes one of the user's fills.
+func (v *VWAP) AddFill(f Fill) {
+	size := f.Size
+	if f.SizeInQuote && !f.Price.IsZero() {
+		size = size.Div(f.Price)
+	}
+	v.Add(f.TradeTime, f.Price, size)
+}
+
+// evict removes entries that have fallen out of the window
+func (v *VWAP) evict() {
+	if v.Window <= 0 {
+		return
+	}
+	cutoff := v.newest.Add(-v.Window)
+
+	n := 0
+	for n < len(v.entries) && v.entries[n].time.Before(cutoff) {
+		v.value = v.value.Sub(v.entries[n].value)
+		v.volume = v.volume.Sub(v.entries[n].size)
+		n++
+	}
+	v.entries = v.entries[n:]
+}
+
+// Value returns the volume weighted average price, and false if there is no volume in the window.
+func (v *VWAP) Value() (price decimal.Decimal, ok bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.volume.IsPositive() {
+		return
+	}
+	return v.value.Div(v.volume), true
+}
+
+// Volume returns the total size of the trades in the window.
+func (v *VWAP) Volume() decimal.Decimal {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.volume
+}
@@ -0,0 +1,600 @@
+package coinbasetrade
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+)
+
+type Channel string
+
+const (
+	wsMessageBuffer = 100 // how many unread messages to hold, by default
+
+	UserChannel Channel = "user"
+
+	// ResyncChannel is used for messages generated by this library rather than the server. One is
+	// delivered after a dropped connection has been re-established and every channel resubscribed.
+	// Anything sent while disconnected was missed, so state built from the feed (order books, order
+	// status, etc) should be rebuilt.
+	ResyncChannel Channel = "resync"
+)
+
+// OverflowPolicy decides what a websocket does with a new message when the Messages channel is full
+// because they aren't being read quickly enough.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room. Nothing is lost, but while waiting nothing is read
+	// from the connection, so a consumer that stays slow for long enough can cause the server to
+	// drop it.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest unread message to make room, so the consumer always
+	// gets the latest data.
+	OverflowDropOldest
+
+	// OverflowDropNewest discards the new message.
+	OverflowDropNewest
+)
+
+// Websocket is a connection to the Advanced Trade websocket feed. Messages received from any
+// subscribed channel are delivered, in order, on the channel returned by Messages().
+type Websocket struct {
+	URL    string // i.e. wss://advanced-trade-ws.coinbase.com
+	client *Client
+
+	Reconnect            bool          // reconnect and resubscribe if the connection drops (default true)
+	ReconnectMinDelay    time.Duration // wait before the first reconnect attempt (default 1s)
+	ReconnectMaxDelay    time.Duration // the wait doubles after each failed attempt, up to this (default 1m)
+	MaxReconnectAttempts int           // give up after this many failed attempts in a row; 0 means never
+
+	// HeartbeatTimeout, if set before connecting, subscribes to the heartbeats channel and treats
+	// the connection as dead if no message arrives for this long, so it is reconnected (or ended,
+	// if Reconnect is false) rather than waiting for the network to notice. A few seconds is
+	// plenty, as heartbeats are sent every second. OnHeartbeatTimeout is called when it happens.
+	HeartbeatTimeout   time.Duration
+	OnHeartbeatTimeout func()
+
+	// BufferSize is how many unread messages are held (default 100), and Overflow what happens
+	// when that many are waiting. Dropped messages are counted by Dropped. A ResyncChannel message
+	// always waits for room, though with OverflowDropOldest it may later be discarded to make room
+	// for a newer one. Both must be set before connecting.
+	BufferSize int
+	Overflow   OverflowPolicy
+
+	// OnRawMessage, if set, is called with every frame received, before it is decoded, including
+	// ones from channels this package doesn't know about and ones it can't decode. It is called
+	// from the goroutine reading the connection, so it should return quickly.
+	OnRawMessage func(data []byte)
+
+	// ResubscribeOnGap unsubscribes and resubscribes to every channel when a GapChannel message is
+	// delivered, so the server sends new snapshots to rebuild from.
+	ResubscribeOnGap bool
+
+	mu            sync.Mutex // guards the fields below and serializes writes to conn
+	conn          *websocket.Conn
+	closed        bool
+	subscriptions map[Channel][]string
+	err           error
+
+	done     chan struct{} // closed by Close
+	messages chan WebsocketMessage
+	dropped  atomic.Int64
+}
+
+// WebsocketMessage is the envelope shared by every channel. The contents of Events depend on the
+// channel, so use the matching helper (e.g. UserEvents) to decode them.
+type WebsocketMessage struct {
+	Channel     Channel         `json:"channel"`
+	ClientID    string          `json:"client_id"`
+	Timestamp   time.Time       `json:"timestamp"`
+	SequenceNum int64           `json:"sequence_num"`
+	Events      json.RawMessage `json:"events"`
+}
+
+// NewWebsocket creates a websocket connection using the client's credentials. The URL can be
+// overridden with the COINBASE_WS_URL environment variable. Call Connect() before subscribing.
+func (c *Client) NewWebsocket() *Websocket {
+	ws := &Websocket{
+		URL:    os.Getenv("COINBASE_WS_URL"),
+		client: c,
+
+		Reconnect:         true,
+		ReconnectMinDelay: time.Second,
+		ReconnectMaxDelay: time.Minute,
+		BufferSize:        wsMessageBuffer,
+
+		subscriptions: make(map[Channel][]string),
+		done:          make(chan struct{}),
+	}
+	if ws.URL == "" {
+		ws.URL = "wss://advanced-trade-ws.coinbase.com"
+	}
+	return ws
+}
+
+// Connect dials the websocket server and starts reading messages in the background.
+func (ws *Websocket) Connect() (err error) {
+	if err = ws.dial(); err != nil {
+		return
+	}
+
+	if ws.HeartbeatTimeout > 0 {
+		if err = ws.Subscribe(HeartbeatsChannel); err != nil {
+			ws.Close()
+			return
+		}
+	}
+
+	ws.messages = make(chan WebsocketMessage, ws.BufferSize)
+	go ws.readLoop()
+	return
+}
+
+// dial opens a new connection to the server, replacing the current one
+func (ws *Websocket) dial() error {
+	dialer := websocket.DefaultDialer
+	if ws.client.transportOptions != nil {
+		dialer = ws.client.transportOptions.websocketDialer()
+	}
+
+	conn, _, err := dialer.Dial(ws.URL, nil)
+	if err != nil {
+		return formatError("websocket dial", err)
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		conn.Close()
+		return errors.New("websocket is closed")
+	}
+	ws.conn = conn
+	return nil
+}
+
+// Messages returns the channel that incoming messages are delivered on. The channel is closed
+// when the connection ends for good; call Err() to find out why.
+func (ws *Websocket) Messages() <-chan WebsocketMessage {
+	return ws.messages
+}
+
+// Dropped returns how many messages have been discarded because the Messages channel was full.
+func (ws *Websocket) Dropped() int64 {
+	return ws.dropped.Load()
+}
+
+// Err returns the error that ended the connection, if any.
+func (ws *Websocket) Err() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.err
+}
+
+// setErr records the error that ended the connection
+func (ws *Websocket) setErr(err error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.err = err
+}
+
+// Close shuts down the connection. It will not be reconnected.
+func (ws *Websocket) Close() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if !ws.closed {
+		ws.closed = true
+		close(ws.done)
+	}
+	if ws.conn == nil {
+		return nil
+	}
+	return ws.conn.Close()
+}
+
+func (ws *Websocket) isClosed() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.closed
+}
+
+// Subscribe starts receiving messages from a channel for the given products. The user channel
+// may be subscribed to without any products to receive updates for all of them. Subscriptions are
+// remembered, and restored if the connection is re-established.
+func (ws *Websocket) Subscribe(channel Channel, productIDs ...string) (err error) {
+	if err = ws.send("subscribe", channel, productIDs); err != nil {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.subscriptions[channel] = mergeProducts(ws.subscriptions[channel], productIDs)
+	return
+}
+
+// Unsubscribe stops receiving messages from a channel for the given products, or for all of them
+// if none are given, without reconnecting. Products can be subscribed to and unsubscribed from
+// freely on a live connection, e.g. to rotate through a list of products.
+func (ws *Websocket) Unsubscribe(channel Channel, productIDs ...string) (err error) {
+	ws.mu.Lock()
+	subscribed, ok := ws.subscriptions[channel]
+	ws.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	all := len(productIDs) == 0
+	if all {
+		productIDs = subscribed
+	}
+	if err = ws.send("unsubscribe", channel, productIDs); err != nil {
+		return
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if remaining := removeProducts(ws.subscriptions[channel], productIDs); all || len(subscribed) > 0 && len(remaining) == 0 {
+		delete(ws.subscriptions, channel)
+	} else {
+		ws.subscriptions[channel] = remaining
+	}
+	return
+}
+
+// Subscriptions returns the products subscribed to on each channel. A channel subscribed to
+// without any products has an empty list.
+func (ws *Websocket) Subscriptions() map[Channel][]string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	subs := make(map[Channel][]string, len(ws.subscriptions))
+	for k, v := range ws.subscriptions {
+		subs[k] = append([]string{}, v...)
+	}
+	return subs
+}
+
+// mergeProducts adds any product ids not already in the list
+func mergeProducts(list, add []string) []string {
+	for _, a := range add {
+		found := false
+		for _, v := range list {
+			if v == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// removeProducts returns the list without the given product ids
+func removeProducts(list, remove []string) (remaining []string) {
+	for _, v := range list {
+		found := false
+		for _, r := range remove {
+			if v == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			remaining = append(remaining, v)
+		}
+	}
+	return
+}
+
+// resubscribe restores every subscription on a new connection
+func (ws *Websocket) resubscribe() (err error) {
+	ws.mu.Lock()
+	subs := make(map[Channel][]string, len(ws.subscriptions))
+	for k, v := range ws.subscriptions {
+		subs[k] = v
+	}
+	ws.mu.Unlock()
+
+	for channel, products := range subs {
+		if err = ws.send("subscribe", channel, products); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// send signs and writes a subscription message to the server
+func (ws *Websocket) send(msgType string, channel Channel, productIDs []string) (err error) {
+	if productIDs == nil {
+		productIDs = []string{}
+	}
+
+	msg := struct {
+		Type       string   `json:"type"`
+		ProductIDs []string `json:"product_ids"`
+		Channel    Channel  `json:"channel"`
+		APIKey     string   `json:"api_key,omitempty"`
+		Timestamp  string   `json:"timestamp,omitempty"`
+		Signature  string   `json:"signature,omitempty"`
+		JWT        string   `json:"jwt,omitempty"`
+	}{Type: msgType, ProductIDs: productIDs, Channel: channel}
+
+	if ws.client.usesJWT() {
+		if msg.JWT, err = ws.client.buildJWT(ws.client.primaryKey(), ""); err != nil {
+			err = formatError("generate websocket jwt", err)
+			return
+		}
+	} else {
+		msg.APIKey = ws.client.Key
+		msg.Timestamp = strconv.FormatInt(ws.client.now().Unix(), 10)
+		if msg.Signature, err = ws.sign(msg.Timestamp, channel, productIDs); err != nil {
+			err = formatError("generate websocket signature", err)
+			return
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn == nil {
+		return errors.New("websocket is not connected")
+	}
+	if err = ws.conn.WriteJSON(msg); err != nil {
+		err = formatError("websocket "+msgType, err)
+	}
+	return
+}
+
+// sign creates the signature for a websocket subscription, which covers the timestamp, channel,
+// and comma-separated product ids
+func (ws *Websocket) sign(timestamp string, channel Channel, productIDs []string) (sig string, err error) {
+	hash := hmac.New(sha256.New, []byte(ws.client.Secret))
+
+	message := fmt.Sprintf("%s%s%s", timestamp, channel, strings.Join(productIDs, ","))
+	if _, err = hash.Write([]byte(message)); err != nil {
+		return
+	}
+	sig = hex.EncodeToString(hash.Sum(nil))
+	return
+}
+
+// readLoop reads messages until the connection fails for good, delivering each one to the
+// messages channel
+func (ws *Websocket) readLoop() {
+	defer close(ws.messages)
+
+	var seq sequenceTracker
+	for {
+		ws.mu.Lock()
+		conn := ws.conn
+		ws.mu.Unlock()
+
+		if ws.HeartbeatTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(ws.HeartbeatTimeout))
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && ws.HeartbeatTimeout > 0 {
+				err = fmt.Errorf("%w for %s", ErrHeartbeatTimeout, ws.HeartbeatTimeout)
+				if ws.OnHeartbeatTimeout != nil {
+					ws.OnHeartbeatTimeout()
+				}
+				// a timed out read leaves the connection unusable
+				conn.Close()
+			}
+			if !ws.reconnect(err) {
+				return
+			}
+			ws.deliver(WebsocketMessage{Channel: ResyncChannel, Timestamp: time.Now()})
+			seq = sequenceTracker{} // a new connection starts its own sequence
+			continue
+		}
+
+		if ws.OnRawMessage != nil {
+			ws.OnRawMessage(data)
+		}
+
+		var msg WebsocketMessage
+		if err = json.Unmarshal(data, &msg); err != nil {
+			ws.client.log(slog.LevelError, "could not decode websocket message", "error", err, "message", string(data))
+			continue
+		}
+
+		// errors are sent in their own format, without a channel. These are usually caused by a bad
+		// subscription, so reconnecting wouldn't help.
+		if msg.Channel == "" {
+			e := struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			}{}
+			if json.Unmarshal(data, &e) == nil && e.Type == "error" {
+				ws.setErr(formatError("websocket", errors.New(e.Message)))
+				ws.Close()
+				return
+			}
+			continue
+		}
+
+		if gap, ok := seq.check(msg.SequenceNum); ok {
+			ws.reportGap(gap)
+		}
+
+		if ws.client.Tracer != nil {
+			end := ws.client.Tracer.StartWebsocketMessage(msg)
+			ws.deliver(msg)
+			end()
+			continue
+		}
+		ws.deliver(msg)
+	}
+}
+
+// deliver puts a message on the messages channel, following the overflow policy if it is full.
+// A message that is waiting for room is given up on if the websocket is closed.
+func (ws *Websocket) deliver(msg WebsocketMessage) {
+	if ws.Overflow == OverflowBlock || msg.Channel == ResyncChannel {
+		select {
+		case ws.messages <- msg:
+		case <-ws.done:
+		}
+		return
+	}
+
+	for {
+		select {
+		case ws.messages <- msg:
+			return
+		default:
+		}
+
+		if ws.Overflow == OverflowDropNewest {
+			ws.drop(msg)
+			return
+		}
+
+		// the consumer may take the oldest message first, in which case there is room now
+		select {
+		case old := <-ws.messages:
+			ws.drop(old)
+		default:
+		}
+	}
+}
+
+// drop counts a discarded message
+func (ws *Websocket) drop(msg WebsocketMessage) {
+	if n := ws.dropped.Add(1); n == 1 || n%1000 == 0 {
+		ws.client.log(slog.LevelWarn, "websocket messages are not being read quickly enough, dropping them", "channel", msg.Channel, "dropped", n)
+	}
+}
+
+// reconnect re-establishes the connection with exponential backoff and restores all subscriptions.
+// It returns false if the connection was closed on purpose, reconnecting is disabled, or the
+// maximum number of attempts was reached.
+func (ws *Websocket) reconnect(cause error) bool {
+	if ws.isClosed() {
+		return false
+	}
+	if !ws.Reconnect {
+		ws.setErr(formatError("websocket read", cause))
+		return false
+	}
+
+	delay := ws.ReconnectMinDelay
+	for attempt := 1; ; attempt++ {
+		ws.client.log(slog.LevelWarn, "websocket disconnected, reconnecting", "error", cause, "wait", delay, "attempt", attempt)
+		if ws.client.Metrics != nil {
+			ws.client.Metrics.ObserveWebsocketReconnect(attempt)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ws.done:
+			timer.Stop()
+			return false
+		}
+
+		err := ws.dial()
+		if err == nil {
+			if err = ws.resubscribe(); err == nil {
+				return true
+			}
+		}
+
+		if ws.MaxReconnectAttempts > 0 && attempt >= ws.MaxReconnectAttempts {
+			ws.setErr(formatError("websocket reconnect", err))
+			return false
+		}
+
+		cause = err
+		if delay *= 2; delay > ws.ReconnectMaxDelay {
+			delay = ws.ReconnectMaxDelay
+		}
+	}
+}
+
+// UserOrder is an order as reported by the user channel. The embedded Order only has the fields
+// included in the websocket feed populated.
+type UserOrder struct {
+	Order
+	LeavesQuantity decimal.Decimal
+}
+
+// UserEvent is a snapshot or update of the user's orders.
+type UserEvent struct {
+	Type   string // "snapshot" or "update"
+	Orders []UserOrder
+}
+
+// UserEvents decodes the events from a user channel message. Note that the user channel reports
+// the cumulative state of each order rather than individual fills; use ListFills with the order id
+// if you need the details of each match.
+func (m WebsocketMessage) UserEvents() (events []UserEvent, err error) {
+	if m.Channel != UserChannel {
+		err = fmt.Errorf("cannot decode %s message as user events", m.Channel)
+		return
+	}
+
+	var raw []struct {
+		Type   string `json:"type"`
+		Orders []struct {
+			ID                 string          `json:"order_id"`
+			ClientOrderID      string          `json:"client_order_id"`
+			CumulativeQuantity decimal.Decimal `json:"cumulative_quantity"`
+			LeavesQuantity     decimal.Decimal `json:"leaves_quantity"`
+			AveragePrice       decimal.Decimal `json:"avg_price"`
+			TotalFees          decimal.Decimal `json:"total_fees"`
+			Status             OrderStatus     `json:"status"`
+			ProductID          string          `json:"product_id"`
+			CreationTime       time.Time       `json:"creation_time"`
+			Side               Side            `json:"order_side"`
+			Type               OrderType       `json:"order_type"`
+		} `json:"orders"`
+	}
+	if err = json.Unmarshal(m.Events, &raw); err != nil {
+		err = formatError("unmarshal user events", err)
+		return
+	}
+
+	for _, e := range raw {
+		event := UserEvent{Type: e.Type}
+		for _, o := range e.Orders {
+			event.Orders = append(event.Orders, UserOrder{
+				Order: Order{
+					ID:                 o.ID,
+					Product:            o.ProductID,
+					ClientOrderID:      o.ClientOrderID,
+					Side:               o.Side,
+					Status:             o.Status,
+					CreatedTime:        o.CreationTime,
+					FilledSize:         o.CumulativeQuantity,
+					AverageFilledPrice: o.AveragePrice,
+					TotalFees:          o.TotalFees,
+					Type:               o.Type,
+				},
+				LeavesQuantity: o.LeavesQuantity,
+			})
+		}
+		events = append(events, event)
+	}
+	return
+}
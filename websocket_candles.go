@@ -0,0 +1,122 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CandlesChannel sends five minute candles for the subscribed products, updated every second.
+const CandlesChannel Channel = "candles"
+
+// CandleUpdate is the latest state of a candle for one product.
+type CandleUpdate struct {
+	ProductID string
+	Candle    Candle
+	Complete  bool // true once the candle's period has ended and it will not change again
+}
+
+// CandleEvent is a snapshot or update from the candles channel.
+type CandleEvent struct {
+	Type    string // "snapshot" or "update"
+	Updates []CandleUpdate
+}
+
+// CandleEvents decodes the events from a candles channel message. Complete is never set on these
+// updates, because the message on its own doesn't say whether a candle has closed; use a
+// CandleStream for that.
+func (m WebsocketMessage) CandleEvents() (events []CandleEvent, err error) {
+	if m.Channel != CandlesChannel {
+		err = fmt.Errorf("cannot decode %s message as candle events", m.Channel)
+		return
+	}
+
+	// Candle has its own unmarshaler, so the product ids are decoded separately
+	var candles []struct {
+		Type    string   `json:"type"`
+		Candles []Candle `json:"candles"`
+	}
+	var products []struct {
+		Candles []struct {
+			ProductID string `json:"product_id"`
+		} `json:"candles"`
+	}
+	if err = json.Unmarshal(m.Events, &candles); err == nil {
+		err = json.Unmarshal(m.Events, &products)
+	}
+	if err != nil {
+		err = formatError("unmarshal candle events", err)
+		return
+	}
+
+	for i, e := range candles {
+		event := CandleEvent{Type: e.Type}
+		for j, c := range e.Candles {
+			event.Updates = append(event.Updates, CandleUpdate{
+				ProductID: products[i].Candles[j].ProductID,
+				Candle:    c,
+			})
+		}
+		events = append(events, event)
+	}
+	return
+}
+
+// CandleStream follows candles channel messages and works out when each candle has closed. It is
+// safe to use from multiple goroutines.
+type CandleStream struct {
+	mu      sync.Mutex
+	current map[string]Candle // the in-progress candle for each product
+}
+
+// NewCandleStream creates an empty CandleStream.
+func NewCandleStream() *CandleStream {
+	return &CandleStream{current: make(map[string]Candle)}
+}
+
+// Apply takes a websocket message and returns the candle updates it contains, oldest first.
+// Messages from other channels are ignored. When a product's candle is replaced by one for the next
+// period, the final state of the old candle is returned first with Complete set.
+func (s *CandleStream) Apply(msg WebsocketMessage) (updates []CandleUpdate, err error) {
+	if msg.Channel == ResyncChannel || msg.Channel == GapChannel {
+		// updates may have been missed, so the candles we have can't be trusted to be final
+		s.mu.Lock()
+		s.current = make(map[string]Candle)
+		s.mu.Unlock()
+		return
+	}
+	if msg.Channel != CandlesChannel {
+		return
+	}
+
+	var events []CandleEvent
+	if events, err = msg.CandleEvents(); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		for _, u := range e.Updates {
+			prev, ok := s.current[u.ProductID]
+			switch {
+			case ok && u.Candle.StartUnix < prev.StartUnix:
+				continue // an old candle arriving late
+			case ok && u.Candle.StartUnix > prev.StartUnix:
+				updates = append(updates, CandleUpdate{ProductID: u.ProductID, Candle: prev, Complete: true})
+			}
+			s.current[u.ProductID] = u.Candle
+			updates = append(updates, u)
+		}
+	}
+	return
+}
+
+// Current returns the in-progress candle for a product, and false if none has been received.
+func (s *CandleStream) Current(productID string) (c Candle, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok = s.current[productID]
+	return
+}
@@ -0,0 +1,62 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// FuturesBalanceSummaryChannel sends the balances and margin of the user's futures account (held
+// with the FCM) whenever they change.
+const FuturesBalanceSummaryChannel Channel = "futures_balance_summary"
+
+// MarginWindow is the margin required in one of the futures margin windows. Intraday margin
+// requirements are usually lower than overnight ones.
+type MarginWindow struct {
+	Type                        string          `json:"margin_window_type"` // e.g. "FCM_MARGIN_WINDOW_TYPE_INTRADAY"
+	MarginLevel                 string          `json:"margin_level"`       // e.g. "MARGIN_LEVEL_TYPE_BASE"
+	InitialMargin               decimal.Decimal `json:"initial_margin"`
+	MaintenanceMargin           decimal.Decimal `json:"maintenance_margin"`
+	LiquidationBufferPercentage decimal.Decimal `json:"liquidation_buffer_percentage"`
+	TotalHold                   decimal.Decimal `json:"total_hold"`
+	FuturesBuyingPower          decimal.Decimal `json:"futures_buying_power"`
+}
+
+// FuturesBalanceSummary is the balance and margin of the user's futures account, in USD.
+type FuturesBalanceSummary struct {
+	FuturesBuyingPower          decimal.Decimal `json:"futures_buying_power"`
+	TotalUSDBalance             decimal.Decimal `json:"total_usd_balance"` // spot (CBI) and futures (CFM) combined
+	CBIUSDBalance               decimal.Decimal `json:"cbi_usd_balance"`
+	CFMUSDBalance               decimal.Decimal `json:"cfm_usd_balance"`
+	TotalOpenOrdersHoldAmount   decimal.Decimal `json:"total_open_orders_hold_amount"`
+	UnrealizedPNL               decimal.Decimal `json:"unrealized_pnl"`
+	DailyRealizedPNL            decimal.Decimal `json:"daily_realized_pnl"`
+	InitialMargin               decimal.Decimal `json:"initial_margin"`
+	AvailableMargin             decimal.Decimal `json:"available_margin"`
+	LiquidationThreshold        decimal.Decimal `json:"liquidation_threshold"`
+	LiquidationBufferAmount     decimal.Decimal `json:"liquidation_buffer_amount"`
+	LiquidationBufferPercentage decimal.Decimal `json:"liquidation_buffer_percentage"`
+	IntradayMargin              MarginWindow    `json:"intraday_margin_window_measure"`
+	OvernightMargin             MarginWindow    `json:"overnight_margin_window_measure"`
+}
+
+// FuturesBalanceSummaryEvent is a snapshot or update from the futures_balance_summary channel.
+type FuturesBalanceSummaryEvent struct {
+	Type    string                `json:"type"` // "snapshot" or "update"
+	Summary FuturesBalanceSummary `json:"fcm_balance_summary"`
+}
+
+// FuturesBalanceSummaryEvents decodes the events from a futures_balance_summary channel message.
+// The latest event has the current balances.
+func (m WebsocketMessage) FuturesBalanceSummaryEvents() (events []FuturesBalanceSummaryEvent, err error) {
+	if m.Channel != FuturesBalanceSummaryChannel {
+		err = fmt.Errorf("cannot decode %s message as futures balance summary events", m.Channel)
+		return
+	}
+
+	if err = json.Unmarshal(m.Events, &events); err != nil {
+		err = formatError("unmarshal futures balance summary events", err)
+	}
+	return
+}
@@ -0,0 +1,81 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// GapChannel is used for messages generated by this library rather than the server. One is
+// delivered when the sequence numbers of the messages received skip ahead, meaning some were
+// missed, just before the message that revealed the gap. State built from the feed (order books,
+// order status, etc) should be rebuilt. Decode it with SequenceGap.
+const GapChannel Channel = "gap"
+
+// SequenceGap describes messages missed on a websocket connection.
+type SequenceGap struct {
+	Expected int64 `json:"expected"` // the sequence number that should have come next
+	Received int64 `json:"received"` // the sequence number that came instead
+}
+
+// Missed returns how many messages were missed.
+func (g SequenceGap) Missed() int64 {
+	return g.Received - g.Expected
+}
+
+// SequenceGap decodes a GapChannel message.
+func (m WebsocketMessage) SequenceGap() (gap SequenceGap, err error) {
+	if m.Channel != GapChannel {
+		err = fmt.Errorf("cannot decode %s message as a sequence gap", m.Channel)
+		return
+	}
+
+	if err = json.Unmarshal(m.Events, &gap); err != nil {
+		err = formatError("unmarshal sequence gap", err)
+	}
+	return
+}
+
+// sequenceTracker follows the sequence numbers on one connection
+type sequenceTracker struct {
+	last int64
+	have bool
+}
+
+// check records a message's sequence number, and returns the gap if any messages were skipped.
+// Messages that arrive late or twice are ignored.
+func (t *sequenceTracker) check(seq int64) (gap SequenceGap, ok bool) {
+	if t.have && seq <= t.last {
+		return
+	}
+	if t.have && seq != t.last+1 {
+		gap, ok = SequenceGap{Expected: t.last + 1, Received: seq}, true
+	}
+	t.last, t.have = seq, true
+	return
+}
+
+// reportGap delivers a GapChannel message, and resubscribes to everything if ResubscribeOnGap is
+// set so the server sends new snapshots
+func (ws *Websocket) reportGap(gap SequenceGap) {
+	ws.client.log(slog.LevelWarn, "websocket messages were missed", "expected", gap.Expected, "received", gap.Received)
+
+	events, _ := json.Marshal(gap)
+	ws.deliver(WebsocketMessage{Channel: GapChannel, Timestamp: time.Now(), Events: events})
+
+	if !ws.ResubscribeOnGap {
+		return
+	}
+	for channel, products := range ws.Subscriptions() {
+		err := ws.send("unsubscribe", channel, products)
+		if err == nil {
+			err = ws.send("subscribe", channel, products)
+		}
+		if err != nil {
+			// the connection has probably failed, and the next read will reconnect it
+			ws.client.log(slog.LevelError, "could not resubscribe after missing messages", "channel", channel, "error", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeartbeatsChannel sends a message every second, so a connection that has silently died can be
+// detected even when no other channel is busy.
+const HeartbeatsChannel Channel = "heartbeats"
+
+// ErrHeartbeatTimeout is the cause given when no message arrives within a websocket's
+// HeartbeatTimeout.
+var ErrHeartbeatTimeout = errors.New("no heartbeat received")
+
+// heartbeatTimeLayout is the format of the server's current_time, which is Go's default time format
+const heartbeatTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+// Heartbeat is a single heartbeat from the server.
+type Heartbeat struct {
+	CurrentTime time.Time
+	Counter     int64 // increases by one with each heartbeat on a connection
+}
+
+// HeartbeatEvents decodes the events from a heartbeats channel message.
+func (m WebsocketMessage) HeartbeatEvents() (heartbeats []Heartbeat, err error) {
+	if m.Channel != HeartbeatsChannel {
+		err = fmt.Errorf("cannot decode %s message as heartbeats", m.Channel)
+		return
+	}
+
+	var raw []struct {
+		CurrentTime string          `json:"current_time"`
+		Counter     json.RawMessage `json:"heartbeat_counter"` // sent as either a string or a number
+	}
+	if err = json.Unmarshal(m.Events, &raw); err != nil {
+		err = formatError("unmarshal heartbeats", err)
+		return
+	}
+
+	for _, e := range raw {
+		var h Heartbeat
+		if h.Counter, err = strconv.ParseInt(strings.Trim(string(e.Counter), `"`), 10, 64); err != nil {
+			err = formatError("parse heartbeat counter", err)
+			return
+		}
+
+		// the time may have a monotonic clock reading (" m=+123.456") on the end
+		t, _, _ := strings.Cut(e.CurrentTime, " m=")
+		if h.CurrentTime, err = time.Parse(heartbeatTimeLayout, t); err != nil {
+			err = formatError("parse heartbeat time", err)
+			return
+		}
+		heartbeats = append(heartbeats, h)
+	}
+	return
+}
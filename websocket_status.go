@@ -0,0 +1,108 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// StatusChannel sends the trading status of the subscribed products, and an update whenever one
+// changes (e.g. trading is halted or the product is delisted).
+const StatusChannel Channel = "status"
+
+// ProductStatus is a product's status as reported by the status channel.
+type ProductStatus struct {
+	ID             string          `json:"id"`
+	ProductType    string          `json:"product_type"` // i.e. "SPOT"
+	BaseCurrency   string          `json:"base_currency"`
+	QuoteCurrency  string          `json:"quote_currency"`
+	BaseIncrement  decimal.Decimal `json:"base_increment"`
+	QuoteIncrement decimal.Decimal `json:"quote_increment"`
+	DisplayName    string          `json:"display_name"`
+	Status         string          `json:"status"`         // i.e. "online", "offline", or "delisted"
+	StatusMessage  string          `json:"status_message"` // why trading is limited, e.g. "limit only"
+	MinMarketFunds decimal.Decimal `json:"min_market_funds"`
+}
+
+// Online reports whether the product can be traded normally.
+func (s ProductStatus) Online() bool {
+	return s.Status == "online" && s.StatusMessage == ""
+}
+
+// StatusEvent is a snapshot or update from the status channel.
+type StatusEvent struct {
+	Type     string          `json:"type"` // "snapshot" or "update"
+	Products []ProductStatus `json:"products"`
+}
+
+// StatusEvents decodes the events from a status channel message.
+func (m WebsocketMessage) StatusEvents() (events []StatusEvent, err error) {
+	if m.Channel != StatusChannel {
+		err = fmt.Errorf("cannot decode %s message as status events", m.Channel)
+		return
+	}
+
+	if err = json.Unmarshal(m.Events, &events); err != nil {
+		err = formatError("unmarshal status events", err)
+	}
+	return
+}
+
+// ProductStatusChange is a change to a product's status. Previous is empty the first time a
+// product is seen.
+type ProductStatusChange struct {
+	Previous ProductStatus
+	Current  ProductStatus
+}
+
+// StatusStream follows status channel messages and works out which products' statuses have
+// changed. It is safe to use from multiple goroutines.
+type StatusStream struct {
+	mu       sync.Mutex
+	statuses map[string]ProductStatus
+}
+
+// NewStatusStream creates an empty StatusStream.
+func NewStatusStream() *StatusStream {
+	return &StatusStream{statuses: make(map[string]ProductStatus)}
+}
+
+// Apply takes a websocket message and returns the products whose status or status message has
+// changed, including every product the first time it is seen. Messages from other channels are
+// ignored. The statuses are kept across a reconnect, so a change made while disconnected is
+// reported when the new snapshot arrives.
+func (s *StatusStream) Apply(msg WebsocketMessage) (changes []ProductStatusChange, err error) {
+	if msg.Channel != StatusChannel {
+		return
+	}
+
+	var events []StatusEvent
+	if events, err = msg.StatusEvents(); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		for _, p := range e.Products {
+			prev, ok := s.statuses[p.ID]
+			s.statuses[p.ID] = p
+			if ok && prev.Status == p.Status && prev.StatusMessage == p.StatusMessage {
+				continue
+			}
+			changes = append(changes, ProductStatusChange{Previous: prev, Current: p})
+		}
+	}
+	return
+}
+
+// Status returns the last known status of a product, and false if none has been received.
+func (s *StatusStream) Status(productID string) (status ProductStatus, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok = s.statuses[productID]
+	return
+}
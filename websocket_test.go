@@ -0,0 +1,88 @@
+package coinbasetrade_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	coinbasetrade "github.com/jmacwhyte/go-coinbase-trade"
+)
+
+// TestWebsocketCloseWhileReconnecting checks that Close ends a websocket that is waiting to
+// reconnect straight away, rather than once the wait is over.
+func TestWebsocketCloseWhileReconnecting(t *testing.T) {
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// drop every connection as soon as it is made
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	ws := coinbasetrade.NewClient(&coinbasetrade.ClientConfig{Key: "key", Secret: "secret"}).NewWebsocket()
+	ws.URL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	ws.ReconnectMinDelay = time.Hour
+	ws.Overflow = coinbasetrade.OverflowBlock
+	if err := ws.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// read the error while the reader may be setting it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			ws.Err()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	ws.Close()
+
+	select {
+	case _, ok := <-ws.Messages():
+		for ok {
+			_, ok = <-ws.Messages()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("messages channel wasn't closed after Close")
+	}
+	<-done
+}
+
+// TestWebsocketErr checks that the error that ended a connection can be read while the connection
+// is ending.
+func TestWebsocketErr(t *testing.T) {
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	ws := coinbasetrade.NewClient(&coinbasetrade.ClientConfig{Key: "key", Secret: "secret"}).NewWebsocket()
+	ws.URL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	ws.Reconnect = false
+	if err := ws.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	for ws.Err() == nil {
+		select {
+		case <-ws.Messages():
+		case <-time.After(time.Millisecond):
+		}
+	}
+	for range ws.Messages() {
+	}
+	if ws.Err() == nil {
+		t.Error("Err is nil after the connection ended")
+	}
+}
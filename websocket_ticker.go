@@ -0,0 +1,78 @@
+package coinbasetrade
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// TickerChannel sends the price, best bid and ask, and 24 hour stats of a product every time
+	// it trades.
+	TickerChannel Channel = "ticker"
+
+	// TickerBatchChannel sends the same tickers as TickerChannel, but only every 5 seconds. It is
+	// much lighter when following many products, e.g. for a dashboard.
+	TickerBatchChannel Channel = "ticker_batch"
+)
+
+// Ticker is the latest price and stats of a product, from the ticker or ticker_batch channels.
+type Ticker struct {
+	ProductID             string          `json:"product_id"`
+	Price                 decimal.Decimal `json:"price"`
+	Volume24h             decimal.Decimal `json:"volume_24_h"`
+	Low24h                decimal.Decimal `json:"low_24_h"`
+	High24h               decimal.Decimal `json:"high_24_h"`
+	Low52w                decimal.Decimal `json:"low_52_w"`
+	High52w               decimal.Decimal `json:"high_52_w"`
+	PricePercentChange24h decimal.Decimal `json:"price_percent_chg_24_h"`
+	BestBid               decimal.Decimal `json:"best_bid"`
+	BestBidQuantity       decimal.Decimal `json:"best_bid_quantity"`
+	BestAsk               decimal.Decimal `json:"best_ask"`
+	BestAskQuantity       decimal.Decimal `json:"best_ask_quantity"`
+}
+
+// UnmarshalJSON allows the best bid and ask, their quantities, and the 52 week stats to be empty.
+func (t *Ticker) UnmarshalJSON(data []byte) error {
+	type ticker Ticker // avoids calling this method again
+	aux := struct {
+		*ticker
+		Low52w          string `json:"low_52_w"`
+		High52w         string `json:"high_52_w"`
+		BestBid         string `json:"best_bid"`
+		BestBidQuantity string `json:"best_bid_quantity"`
+		BestAsk         string `json:"best_ask"`
+		BestAskQuantity string `json:"best_ask_quantity"`
+	}{ticker: (*ticker)(t)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	t.Low52w = parseOptionalDecimal(aux.Low52w)
+	t.High52w = parseOptionalDecimal(aux.High52w)
+	t.BestBid = parseOptionalDecimal(aux.BestBid)
+	t.BestBidQuantity = parseOptionalDecimal(aux.BestBidQuantity)
+	t.BestAsk = parseOptionalDecimal(aux.BestAsk)
+	t.BestAskQuantity = parseOptionalDecimal(aux.BestAskQuantity)
+	return nil
+}
+
+// TickerEvent is a snapshot or update from the ticker or ticker_batch channels.
+type TickerEvent struct {
+	Type    string   `json:"type"` // "snapshot" or "update"
+	Tickers []Ticker `json:"tickers"`
+}
+
+// TickerEvents decodes the events from a ticker or ticker_batch channel message.
+func (m WebsocketMessage) TickerEvents() (events []TickerEvent, err error) {
+	if m.Channel != TickerChannel && m.Channel != TickerBatchChannel {
+		err = fmt.Errorf("cannot decode %s message as ticker events", m.Channel)
+		return
+	}
+
+	if err = json.Unmarshal(m.Events, &events); err != nil {
+		err = formatError("unmarshal ticker events", err)
+	}
+	return
+}